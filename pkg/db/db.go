@@ -2,20 +2,59 @@ package db
 
 import (
 	"context"
+	"time"
 
+	"github.com/ne4chelovek/chat_common/pkg/db/prettier"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5"
 )
 
 type Handler func(ctx context.Context) error
 
+// Propagation режим распространения транзакции относительно транзакции, уже находящейся в контексте
+type Propagation int
+
+const (
+	// PropagationRequired присоединяется к текущей транзакции или начинает новую, если её нет
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew всегда начинает новую независимую транзакцию на отдельном соединении
+	PropagationRequiresNew
+	// PropagationMandatory требует наличия текущей транзакции и присоединяется к ней
+	PropagationMandatory
+	// PropagationNever требует отсутствия транзакции и выполняет обработчик без неё
+	PropagationNever
+)
+
 type Client interface {
 	DB() DB
+	// Replica возвращает DB для чтения с реплик
+	Replica() DB
+	// ReplicaStatus возвращает состояние реплик по результатам последней проверки
+	ReplicaStatus() []ReplicaStatus
 	Close() error
 }
+
+// ReplicaStatus состояние реплики
+type ReplicaStatus struct {
+	Host      string
+	Healthy   bool          // участвует ли реплика в балансировке
+	Lag       time.Duration // отставание от мастера
+	Err       error         // ошибка последней проверки
+	CheckedAt time.Time     // время последней проверки, нулевое если проверок не было
+}
 //txmanager
 type TxManager interface {
 	ReadCommitted(ctx context.Context, f Handler) error
+	RepeatableRead(ctx context.Context, f Handler) error
+	Serializable(ctx context.Context, f Handler) error
+	// ReadOnly выполняет обработчик в транзакции READ COMMITTED READ ONLY
+	ReadOnly(ctx context.Context, f Handler) error
+	// Deferrable выполняет обработчик в транзакции SERIALIZABLE READ ONLY DEFERRABLE
+	Deferrable(ctx context.Context, f Handler) error
+	// WithOptions выполняет обработчик в транзакции с произвольными параметрами
+	WithOptions(ctx context.Context, opts pgx.TxOptions, f Handler) error
+	// WithPropagation выполняет обработчик с указанным режимом распространения транзакции
+	WithPropagation(ctx context.Context, p Propagation, opts pgx.TxOptions, f Handler) error
 }
 
 // Query обертка над запросом, хранящая имя запроса и сам запрос
@@ -23,6 +62,19 @@ type TxManager interface {
 type Query struct {
 	Name     string
 	QueryRaw string
+	// MaxRows ограничивает число строк, которое ScanAllContext загружает в память:
+	// 0 означает ограничение DB по умолчанию, отрицательное значение снимает ограничение
+	MaxRows int
+}
+
+// Label возвращает имя запроса, а для запросов без имени - отпечаток нормализованного текста
+// вида fp:<hex>, чтобы такие запросы можно было группировать в метриках, логах и трейсах
+func (q Query) Label() string {
+	if q.Name != "" {
+		return q.Name
+	}
+
+	return "fp:" + prettier.Fingerprint(q.QueryRaw)
 }
 
 // Интерфейс для работы с транзакциями
@@ -30,13 +82,21 @@ type Transactor interface {
 	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 }
 
+// TxJoiner реализуют Transactor, которым нужно проверить, может ли вложенный вызов TxManager
+// присоединиться к транзакции из контекста, например шардированный клиент
+type TxJoiner interface {
+	JoinTx(ctx context.Context, tx pgx.Tx) error
+}
+
 // SQLExecer комбинирует NamedExecer и QueryExecer
 type SQLExecer interface {
 	NamedExecer
 	QueryExecer
 }
 
-// NamedExecer интерфейс для работы с именованными запросами с помощью тегов в структурах
+// NamedExecer интерфейс для работы с именованными запросами с помощью тегов в структурах.
+// Для плейсхолдеров @name аргументы передаются единственным значением Named(...) или pgx.NamedArgs,
+// это работает и для методов QueryExecer.
 type NamedExecer interface {
 	ScanOneContext(ctx context.Context, dest interface{}, q Query, args ...interface{}) error
 	ScanAllContext(ctx context.Context, dest interface{}, q Query, args ...interface{}) error
@@ -49,6 +109,14 @@ type QueryExecer interface {
 	QueryRowContext(ctx context.Context, q Query, args ...interface{}) pgx.Row
 }
 
+// Streamer интерфейс для построчной обработки больших выборок без загрузки их в память
+type Streamer interface {
+	// StreamContext сканирует строки по одной в dest и после каждой вызывает fn.
+	// Если fn возвращает ErrStopStream, обход прекращается без ошибки, любая другая ошибка возвращается.
+	// Выборка закрывается в любом случае.
+	StreamContext(ctx context.Context, dest interface{}, q Query, fn func() error, args ...interface{}) error
+}
+
 // Pinger интерфейс для проверки соединения с БД
 type Pinger interface {
 	Ping(ctx context.Context) error
@@ -57,6 +125,7 @@ type Pinger interface {
 // DB интерфейс для работы с БД
 type DB interface {
 	SQLExecer
+	Streamer
 	Transactor
 	Pinger
 	Close()
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды SQLSTATE, которые классифицируются в ошибки пакета
+const (
+	CodeUniqueViolation      = "23505"
+	CodeForeignKeyViolation  = "23503"
+	CodeCheckViolation       = "23514"
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+	CodeQueryCanceled        = "57014"
+)
+
+// Сентинел-ошибки, с которыми можно сравнивать результат через errors.Is
+var (
+	ErrNotFound             = errors.New("db: not found")
+	ErrUniqueViolation      = errors.New("db: unique violation")
+	ErrForeignKeyViolation  = errors.New("db: foreign key violation")
+	ErrCheckViolation       = errors.New("db: check violation")
+	ErrSerializationFailure = errors.New("db: serialization failure")
+	ErrDeadlock             = errors.New("db: deadlock detected")
+	ErrQueryCanceled        = errors.New("db: query canceled")
+)
+
+// ErrStopStream возвращается из обработчика Streamer.StreamContext для досрочного завершения обхода
+var ErrStopStream = errors.New("db: stop streaming")
+
+// ErrTooManyRows выборка превысила допустимое число строк
+var ErrTooManyRows = errors.New("db: too many rows")
+
+// RowLimitError ошибка превышения допустимого числа строк, совпадает с ErrTooManyRows через errors.Is
+type RowLimitError struct {
+	Limit int
+}
+
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("%v: result exceeds %d rows", ErrTooManyRows, e.Limit)
+}
+
+func (e *RowLimitError) Is(target error) bool {
+	return target == ErrTooManyRows
+}
+
+// Error ошибка выполнения запроса, обогащённая именем запроса и данными из *pgconn.PgError.
+// Совпадает через errors.Is со своей сентинел-ошибкой (Kind) и с исходной ошибкой,
+// а через errors.As позволяет получить как *Error, так и *pgconn.PgError.
+type Error struct {
+	QueryName  string // имя запроса из Query.Name
+	Kind       error  // сентинел-ошибка пакета, nil если ошибка не классифицирована
+	Code       string // SQLSTATE
+	Constraint string // имя нарушенного ограничения, если есть
+	Table      string // имя таблицы, если есть
+	Err        error  // исходная ошибка
+}
+
+func (e *Error) Error() string {
+	msg := e.Err.Error()
+	if e.Constraint != "" {
+		msg = fmt.Sprintf("%s (constraint %s)", msg, e.Constraint)
+	}
+	if e.QueryName != "" {
+		return fmt.Sprintf("query %s: %s", e.QueryName, msg)
+	}
+
+	return msg
+}
+
+// Unwrap возвращает исходную ошибку, чтобы errors.Is/errors.As находили pgx.ErrNoRows и *pgconn.PgError
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибку с сентинел-ошибкой пакета
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// WrapError классифицирует ошибку драйвера и оборачивает её в *Error с именем запроса.
+// nil и уже обёрнутые ошибки возвращаются как есть.
+func WrapError(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *Error
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	e := &Error{
+		QueryName: name,
+		Err:       err,
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), dbscan.NotFound(err):
+		e.Kind = ErrNotFound
+	case errors.As(err, &pgErr):
+		e.Code = pgErr.Code
+		e.Constraint = pgErr.ConstraintName
+		e.Table = pgErr.TableName
+		e.Kind = kindByCode(pgErr.Code)
+	}
+
+	return e
+}
+
+// kindByCode сопоставляет SQLSTATE с сентинел-ошибкой пакета
+func kindByCode(code string) error {
+	switch code {
+	case CodeUniqueViolation:
+		return ErrUniqueViolation
+	case CodeForeignKeyViolation:
+		return ErrForeignKeyViolation
+	case CodeCheckViolation:
+		return ErrCheckViolation
+	case CodeSerializationFailure:
+		return ErrSerializationFailure
+	case CodeDeadlockDetected:
+		return ErrDeadlock
+	case CodeQueryCanceled:
+		return ErrQueryCanceled
+	default:
+		return nil
+	}
+}
+
+// ConstraintName возвращает имя нарушенного ограничения, если ошибка его содержит
+func ConstraintName(err error) string {
+	var dbErr *Error
+	if errors.As(err, &dbErr) {
+		return dbErr.Constraint
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+
+	return ""
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"iter"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+// Get выполняет запрос и сканирует единственную строку в значение типа T.
+// Если строк нет, возвращает ошибку, совпадающую с ErrNotFound.
+func Get[T any](ctx context.Context, db NamedExecer, q Query, args ...any) (T, error) {
+	var v T
+	err := db.ScanOneContext(ctx, &v, q, args...)
+
+	return v, err
+}
+
+// Select выполняет запрос и сканирует все строки в срез значений типа T
+func Select[T any](ctx context.Context, db NamedExecer, q Query, args ...any) ([]T, error) {
+	var v []T
+	err := db.ScanAllContext(ctx, &v, q, args...)
+
+	return v, err
+}
+
+// Exec выполняет команду и возвращает число затронутых строк
+func Exec(ctx context.Context, db QueryExecer, q Query, args ...any) (int64, error) {
+	tag, err := db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Iter выполняет запрос и лениво сканирует строки в значения типа T по одной.
+// Запрос выполняется при начале обхода; выборка закрывается по его окончании,
+// в том числе при досрочном выходе из цикла. Ошибка запроса или сканирования
+// возвращается последним элементом, после которого обход прекращается.
+func Iter[T any](ctx context.Context, db QueryExecer, q Query, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		scanner := pgxscan.NewRowScanner(rows)
+		for rows.Next() {
+			var v T
+			if err = scanner.Scan(&v); err != nil {
+				yield(zero, WrapError(q.Name, err))
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			yield(zero, WrapError(q.Name, err))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NamedParams именованные аргументы запроса с плейсхолдерами @name.
+// Передаются единственным аргументом в методы DB: pgx переписывает запрос в позиционный вид
+// через pgx.QueryRewriter, а логирование подставляет значения по именам.
+type NamedParams struct {
+	v any
+}
+
+// Named оборачивает карту со строковыми ключами или структуру в именованные аргументы.
+// Имена полей структуры берутся из тега db, как в scany; поля без тега получают имя
+// в snake_case, поля с тегом db:"-" пропускаются, встроенные структуры раскрываются.
+func Named(v any) NamedParams {
+	return NamedParams{v: v}
+}
+
+// Args возвращает аргументы в виде pgx.NamedArgs
+func (n NamedParams) Args() (pgx.NamedArgs, error) {
+	switch v := n.v.(type) {
+	case pgx.NamedArgs:
+		return v, nil
+	case map[string]any:
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(n.v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("named args: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+
+	args := pgx.NamedArgs{}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("named args: map key must be string, got %s", rv.Type().Key())
+		}
+
+		iter := rv.MapRange()
+		for iter.Next() {
+			args[iter.Key().String()] = iter.Value().Interface()
+		}
+	case reflect.Struct:
+		structArgs(rv, args)
+	default:
+		return nil, fmt.Errorf("named args: unsupported type %s", rv.Type())
+	}
+
+	return args, nil
+}
+
+// RewriteQuery реализует pgx.QueryRewriter
+func (n NamedParams) RewriteQuery(ctx context.Context, conn *pgx.Conn, sql string, args []any) (string, []any, error) {
+	named, err := n.Args()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return named.RewriteQuery(ctx, conn, sql, args)
+}
+
+// structArgs собирает значения экспортируемых полей структуры
+func structArgs(rv reflect.Value, args pgx.NamedArgs) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if tag == "-" {
+			continue
+		}
+
+		value := rv.Field(i)
+		if field.Anonymous && tag == "" {
+			for value.Kind() == reflect.Pointer && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				structArgs(value, args)
+				continue
+			}
+		}
+
+		name := tag
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+
+		args[name] = value.Interface()
+	}
+}
+
+// snakeCase переводит имя поля в snake_case так же, как scany: UserID -> user_id
+func snakeCase(s string) string {
+	runes := []rune(s)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// NamedValues возвращает значения именованных аргументов, если запрос передан с единственным
+// аргументом NamedParams, pgx.NamedArgs или pgx.StrictNamedArgs
+func NamedValues(args []any) (map[string]any, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	switch v := args[0].(type) {
+	case pgx.NamedArgs:
+		return v, true
+	case pgx.StrictNamedArgs:
+		return v, true
+	case NamedParams:
+		named, err := v.Args()
+		return named, err == nil
+	}
+
+	return nil, false
+}
@@ -0,0 +1,4 @@
+package outbox
+
+//go:generate sh -c "rm -rf mocks && mkdir -p mocks"
+//go:generate minimock -i Publisher -o ./mocks/ -s "_minimock.go"
@@ -0,0 +1,418 @@
+// Code generated by http://github.com/gojuno/minimock (v3.4.5). DO NOT EDIT.
+
+package mocks
+
+//go:generate minimock -i github.com/ne4chelovek/chat_common/pkg/db/outbox.Publisher -o publisher_minimock.go -n PublisherMock -p mocks
+
+import (
+	"context"
+	_ "embed"
+	"sync"
+	mm_atomic "sync/atomic"
+	mm_time "time"
+
+	"github.com/gojuno/minimock/v3"
+	mm_outbox "github.com/ne4chelovek/chat_common/pkg/db/outbox"
+)
+
+// PublisherMock implements mm_outbox.Publisher
+type PublisherMock struct {
+	t          minimock.Tester
+	finishOnce sync.Once
+
+	funcPublish          func(ctx context.Context, msg mm_outbox.Message) (err error)
+	funcPublishOrigin    string
+	inspectFuncPublish   func(ctx context.Context, msg mm_outbox.Message)
+	afterPublishCounter  uint64
+	beforePublishCounter uint64
+	PublishMock          mPublisherMockPublish
+}
+
+// NewPublisherMock returns a mock for mm_outbox.Publisher
+func NewPublisherMock(t minimock.Tester) *PublisherMock {
+	m := &PublisherMock{t: t}
+
+	if controller, ok := t.(minimock.MockController); ok {
+		controller.RegisterMocker(m)
+	}
+
+	m.PublishMock = mPublisherMockPublish{mock: m}
+	m.PublishMock.callArgs = []*PublisherMockPublishParams{}
+
+	t.Cleanup(m.MinimockFinish)
+
+	return m
+}
+
+type mPublisherMockPublish struct {
+	optional           bool
+	mock               *PublisherMock
+	defaultExpectation *PublisherMockPublishExpectation
+	expectations       []*PublisherMockPublishExpectation
+
+	callArgs []*PublisherMockPublishParams
+	mutex    sync.RWMutex
+
+	expectedInvocations       uint64
+	expectedInvocationsOrigin string
+}
+
+// PublisherMockPublishExpectation specifies expectation struct of the Publisher.Publish
+type PublisherMockPublishExpectation struct {
+	mock               *PublisherMock
+	params             *PublisherMockPublishParams
+	paramPtrs          *PublisherMockPublishParamPtrs
+	expectationOrigins PublisherMockPublishExpectationOrigins
+	results            *PublisherMockPublishResults
+	returnOrigin       string
+	Counter            uint64
+}
+
+// PublisherMockPublishParams contains parameters of the Publisher.Publish
+type PublisherMockPublishParams struct {
+	ctx context.Context
+	msg mm_outbox.Message
+}
+
+// PublisherMockPublishParamPtrs contains pointers to parameters of the Publisher.Publish
+type PublisherMockPublishParamPtrs struct {
+	ctx *context.Context
+	msg *mm_outbox.Message
+}
+
+// PublisherMockPublishResults contains results of the Publisher.Publish
+type PublisherMockPublishResults struct {
+	err error
+}
+
+// PublisherMockPublishOrigins contains origins of expectations of the Publisher.Publish
+type PublisherMockPublishExpectationOrigins struct {
+	origin    string
+	originCtx string
+	originMsg string
+}
+
+// Marks this method to be optional. The default behavior of any method with Return() is '1 or more', meaning
+// the test will fail minimock's automatic final call check if the mocked method was not called at least once.
+// Optional() makes method check to work in '0 or more' mode.
+// It is NOT RECOMMENDED to use this option unless you really need it, as default behaviour helps to
+// catch the problems when the expected method call is totally skipped during test run.
+func (mmPublish *mPublisherMockPublish) Optional() *mPublisherMockPublish {
+	mmPublish.optional = true
+	return mmPublish
+}
+
+// Expect sets up expected params for Publisher.Publish
+func (mmPublish *mPublisherMockPublish) Expect(ctx context.Context, msg mm_outbox.Message) *mPublisherMockPublish {
+	if mmPublish.mock.funcPublish != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Set")
+	}
+
+	if mmPublish.defaultExpectation == nil {
+		mmPublish.defaultExpectation = &PublisherMockPublishExpectation{}
+	}
+
+	if mmPublish.defaultExpectation.paramPtrs != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by ExpectParams functions")
+	}
+
+	mmPublish.defaultExpectation.params = &PublisherMockPublishParams{ctx, msg}
+	mmPublish.defaultExpectation.expectationOrigins.origin = minimock.CallerInfo(1)
+	for _, e := range mmPublish.expectations {
+		if minimock.Equal(e.params, mmPublish.defaultExpectation.params) {
+			mmPublish.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmPublish.defaultExpectation.params)
+		}
+	}
+
+	return mmPublish
+}
+
+// ExpectCtxParam1 sets up expected param ctx for Publisher.Publish
+func (mmPublish *mPublisherMockPublish) ExpectCtxParam1(ctx context.Context) *mPublisherMockPublish {
+	if mmPublish.mock.funcPublish != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Set")
+	}
+
+	if mmPublish.defaultExpectation == nil {
+		mmPublish.defaultExpectation = &PublisherMockPublishExpectation{}
+	}
+
+	if mmPublish.defaultExpectation.params != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Expect")
+	}
+
+	if mmPublish.defaultExpectation.paramPtrs == nil {
+		mmPublish.defaultExpectation.paramPtrs = &PublisherMockPublishParamPtrs{}
+	}
+	mmPublish.defaultExpectation.paramPtrs.ctx = &ctx
+	mmPublish.defaultExpectation.expectationOrigins.originCtx = minimock.CallerInfo(1)
+
+	return mmPublish
+}
+
+// ExpectMsgParam2 sets up expected param msg for Publisher.Publish
+func (mmPublish *mPublisherMockPublish) ExpectMsgParam2(msg mm_outbox.Message) *mPublisherMockPublish {
+	if mmPublish.mock.funcPublish != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Set")
+	}
+
+	if mmPublish.defaultExpectation == nil {
+		mmPublish.defaultExpectation = &PublisherMockPublishExpectation{}
+	}
+
+	if mmPublish.defaultExpectation.params != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Expect")
+	}
+
+	if mmPublish.defaultExpectation.paramPtrs == nil {
+		mmPublish.defaultExpectation.paramPtrs = &PublisherMockPublishParamPtrs{}
+	}
+	mmPublish.defaultExpectation.paramPtrs.msg = &msg
+	mmPublish.defaultExpectation.expectationOrigins.originMsg = minimock.CallerInfo(1)
+
+	return mmPublish
+}
+
+// Inspect accepts an inspector function that has same arguments as the Publisher.Publish
+func (mmPublish *mPublisherMockPublish) Inspect(f func(ctx context.Context, msg mm_outbox.Message)) *mPublisherMockPublish {
+	if mmPublish.mock.inspectFuncPublish != nil {
+		mmPublish.mock.t.Fatalf("Inspect function is already set for PublisherMock.Publish")
+	}
+
+	mmPublish.mock.inspectFuncPublish = f
+
+	return mmPublish
+}
+
+// Return sets up results that will be returned by Publisher.Publish
+func (mmPublish *mPublisherMockPublish) Return(err error) *PublisherMock {
+	if mmPublish.mock.funcPublish != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Set")
+	}
+
+	if mmPublish.defaultExpectation == nil {
+		mmPublish.defaultExpectation = &PublisherMockPublishExpectation{mock: mmPublish.mock}
+	}
+	mmPublish.defaultExpectation.results = &PublisherMockPublishResults{err}
+	mmPublish.defaultExpectation.returnOrigin = minimock.CallerInfo(1)
+	return mmPublish.mock
+}
+
+// Set uses given function f to mock the Publisher.Publish method
+func (mmPublish *mPublisherMockPublish) Set(f func(ctx context.Context, msg mm_outbox.Message) (err error)) *PublisherMock {
+	if mmPublish.defaultExpectation != nil {
+		mmPublish.mock.t.Fatalf("Default expectation is already set for the Publisher.Publish method")
+	}
+
+	if len(mmPublish.expectations) > 0 {
+		mmPublish.mock.t.Fatalf("Some expectations are already set for the Publisher.Publish method")
+	}
+
+	mmPublish.mock.funcPublish = f
+	mmPublish.mock.funcPublishOrigin = minimock.CallerInfo(1)
+	return mmPublish.mock
+}
+
+// When sets expectation for the Publisher.Publish which will trigger the result defined by the following
+// Then helper
+func (mmPublish *mPublisherMockPublish) When(ctx context.Context, msg mm_outbox.Message) *PublisherMockPublishExpectation {
+	if mmPublish.mock.funcPublish != nil {
+		mmPublish.mock.t.Fatalf("PublisherMock.Publish mock is already set by Set")
+	}
+
+	expectation := &PublisherMockPublishExpectation{
+		mock:               mmPublish.mock,
+		params:             &PublisherMockPublishParams{ctx, msg},
+		expectationOrigins: PublisherMockPublishExpectationOrigins{origin: minimock.CallerInfo(1)},
+	}
+	mmPublish.expectations = append(mmPublish.expectations, expectation)
+	return expectation
+}
+
+// Then sets up Publisher.Publish return parameters for the expectation previously defined by the When method
+func (e *PublisherMockPublishExpectation) Then(err error) *PublisherMock {
+	e.results = &PublisherMockPublishResults{err}
+	return e.mock
+}
+
+// Times sets number of times Publisher.Publish should be invoked
+func (mmPublish *mPublisherMockPublish) Times(n uint64) *mPublisherMockPublish {
+	if n == 0 {
+		mmPublish.mock.t.Fatalf("Times of PublisherMock.Publish mock can not be zero")
+	}
+	mm_atomic.StoreUint64(&mmPublish.expectedInvocations, n)
+	mmPublish.expectedInvocationsOrigin = minimock.CallerInfo(1)
+	return mmPublish
+}
+
+func (mmPublish *mPublisherMockPublish) invocationsDone() bool {
+	if len(mmPublish.expectations) == 0 && mmPublish.defaultExpectation == nil && mmPublish.mock.funcPublish == nil {
+		return true
+	}
+
+	totalInvocations := mm_atomic.LoadUint64(&mmPublish.mock.afterPublishCounter)
+	expectedInvocations := mm_atomic.LoadUint64(&mmPublish.expectedInvocations)
+
+	return totalInvocations > 0 && (expectedInvocations == 0 || expectedInvocations == totalInvocations)
+}
+
+// Publish implements mm_outbox.Publisher
+func (mmPublish *PublisherMock) Publish(ctx context.Context, msg mm_outbox.Message) (err error) {
+	mm_atomic.AddUint64(&mmPublish.beforePublishCounter, 1)
+	defer mm_atomic.AddUint64(&mmPublish.afterPublishCounter, 1)
+
+	mmPublish.t.Helper()
+
+	if mmPublish.inspectFuncPublish != nil {
+		mmPublish.inspectFuncPublish(ctx, msg)
+	}
+
+	mm_params := PublisherMockPublishParams{ctx, msg}
+
+	// Record call args
+	mmPublish.PublishMock.mutex.Lock()
+	mmPublish.PublishMock.callArgs = append(mmPublish.PublishMock.callArgs, &mm_params)
+	mmPublish.PublishMock.mutex.Unlock()
+
+	for _, e := range mmPublish.PublishMock.expectations {
+		if minimock.Equal(*e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.err
+		}
+	}
+
+	if mmPublish.PublishMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmPublish.PublishMock.defaultExpectation.Counter, 1)
+		mm_want := mmPublish.PublishMock.defaultExpectation.params
+		mm_want_ptrs := mmPublish.PublishMock.defaultExpectation.paramPtrs
+
+		mm_got := PublisherMockPublishParams{ctx, msg}
+
+		if mm_want_ptrs != nil {
+
+			if mm_want_ptrs.ctx != nil && !minimock.Equal(*mm_want_ptrs.ctx, mm_got.ctx) {
+				mmPublish.t.Errorf("PublisherMock.Publish got unexpected parameter ctx, expected at\n%s:\nwant: %#v\n got: %#v%s\n",
+					mmPublish.PublishMock.defaultExpectation.expectationOrigins.originCtx, *mm_want_ptrs.ctx, mm_got.ctx, minimock.Diff(*mm_want_ptrs.ctx, mm_got.ctx))
+			}
+
+			if mm_want_ptrs.msg != nil && !minimock.Equal(*mm_want_ptrs.msg, mm_got.msg) {
+				mmPublish.t.Errorf("PublisherMock.Publish got unexpected parameter msg, expected at\n%s:\nwant: %#v\n got: %#v%s\n",
+					mmPublish.PublishMock.defaultExpectation.expectationOrigins.originMsg, *mm_want_ptrs.msg, mm_got.msg, minimock.Diff(*mm_want_ptrs.msg, mm_got.msg))
+			}
+
+		} else if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmPublish.t.Errorf("PublisherMock.Publish got unexpected parameters, expected at\n%s:\nwant: %#v\n got: %#v%s\n",
+				mmPublish.PublishMock.defaultExpectation.expectationOrigins.origin, *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmPublish.PublishMock.defaultExpectation.results
+		if mm_results == nil {
+			mmPublish.t.Fatal("No results are set for the PublisherMock.Publish")
+		}
+		return (*mm_results).err
+	}
+	if mmPublish.funcPublish != nil {
+		return mmPublish.funcPublish(ctx, msg)
+	}
+	mmPublish.t.Fatalf("Unexpected call to PublisherMock.Publish. %v %v", ctx, msg)
+	return
+}
+
+// PublishAfterCounter returns a count of finished PublisherMock.Publish invocations
+func (mmPublish *PublisherMock) PublishAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmPublish.afterPublishCounter)
+}
+
+// PublishBeforeCounter returns a count of PublisherMock.Publish invocations
+func (mmPublish *PublisherMock) PublishBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmPublish.beforePublishCounter)
+}
+
+// Calls returns a list of arguments used in each call to PublisherMock.Publish.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmPublish *mPublisherMockPublish) Calls() []*PublisherMockPublishParams {
+	mmPublish.mutex.RLock()
+
+	argCopy := make([]*PublisherMockPublishParams, len(mmPublish.callArgs))
+	copy(argCopy, mmPublish.callArgs)
+
+	mmPublish.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockPublishDone returns true if the count of the Publish invocations corresponds
+// the number of defined expectations
+func (m *PublisherMock) MinimockPublishDone() bool {
+	if m.PublishMock.optional {
+		// Optional methods provide '0 or more' call count restriction.
+		return true
+	}
+
+	for _, e := range m.PublishMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	return m.PublishMock.invocationsDone()
+}
+
+// MinimockPublishInspect logs each unmet expectation
+func (m *PublisherMock) MinimockPublishInspect() {
+	for _, e := range m.PublishMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to PublisherMock.Publish at\n%s with params: %#v", e.expectationOrigins.origin, *e.params)
+		}
+	}
+
+	afterPublishCounter := mm_atomic.LoadUint64(&m.afterPublishCounter)
+	// if default expectation was set then invocations count should be greater than zero
+	if m.PublishMock.defaultExpectation != nil && afterPublishCounter < 1 {
+		if m.PublishMock.defaultExpectation.params == nil {
+			m.t.Errorf("Expected call to PublisherMock.Publish at\n%s", m.PublishMock.defaultExpectation.returnOrigin)
+		} else {
+			m.t.Errorf("Expected call to PublisherMock.Publish at\n%s with params: %#v", m.PublishMock.defaultExpectation.expectationOrigins.origin, *m.PublishMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcPublish != nil && afterPublishCounter < 1 {
+		m.t.Errorf("Expected call to PublisherMock.Publish at\n%s", m.funcPublishOrigin)
+	}
+
+	if !m.PublishMock.invocationsDone() && afterPublishCounter > 0 {
+		m.t.Errorf("Expected %d calls to PublisherMock.Publish at\n%s but found %d calls",
+			mm_atomic.LoadUint64(&m.PublishMock.expectedInvocations), m.PublishMock.expectedInvocationsOrigin, afterPublishCounter)
+	}
+}
+
+// MinimockFinish checks that all mocked methods have been called the expected number of times
+func (m *PublisherMock) MinimockFinish() {
+	m.finishOnce.Do(func() {
+		if !m.minimockDone() {
+			m.MinimockPublishInspect()
+		}
+	})
+}
+
+// MinimockWait waits for all mocked methods to be called the expected number of times
+func (m *PublisherMock) MinimockWait(timeout mm_time.Duration) {
+	timeoutCh := mm_time.After(timeout)
+	for {
+		if m.minimockDone() {
+			return
+		}
+		select {
+		case <-timeoutCh:
+			m.MinimockFinish()
+			return
+		case <-mm_time.After(10 * mm_time.Millisecond):
+		}
+	}
+}
+
+func (m *PublisherMock) minimockDone() bool {
+	done := true
+	return done &&
+		m.MinimockPublishDone()
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+// Schema DDL таблицы outbox, которую нужно применить миграцией перед использованием пакета
+//
+//go:embed schema.sql
+var Schema string
+
+// ErrTxRequired сообщение можно положить в outbox только внутри транзакции db.TxManager
+var ErrTxRequired = errors.New("outbox: enqueue requires transaction in context")
+
+// Message сообщение, ожидающее доставки
+type Message struct {
+	ID        int64             `db:"id"`
+	Topic     string            `db:"topic"`
+	Key       string            `db:"key"`
+	Payload   []byte            `db:"payload"`
+	Headers   map[string]string `db:"headers"`
+	Attempts  int               `db:"attempts"`
+	CreatedAt time.Time         `db:"created_at"`
+}
+
+// Publisher доставляет сообщения во внешнюю систему, например брокер сообщений
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// Enqueue сохраняет сообщение в outbox в рамках текущей транзакции.
+// Сообщение станет видно релею только после коммита транзакции.
+func Enqueue(ctx context.Context, dbc db.DB, msg Message) error {
+	if _, ok := ctx.Value(pg.TxKey).(pgx.Tx); !ok {
+		return ErrTxRequired
+	}
+
+	headers := msg.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	q := db.Query{
+		Name:     "outbox.Enqueue",
+		QueryRaw: "INSERT INTO outbox (topic, key, payload, headers) VALUES ($1, $2, $3, $4)",
+	}
+
+	_, err := dbc.ExecContext(ctx, q, msg.Topic, msg.Key, msg.Payload, headers)
+	return err
+}
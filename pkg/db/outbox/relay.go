@@ -0,0 +1,179 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultMaxAttempts  = 10
+)
+
+// Option настраивает релей
+type Option func(r *Relay)
+
+// Relay забирает недоставленные сообщения из outbox и передаёт их в Publisher.
+// Сообщения блокируются через FOR UPDATE SKIP LOCKED, поэтому несколько релеев
+// могут работать параллельно, не отправляя одно и то же сообщение дважды.
+type Relay struct {
+	db           db.DB
+	txManager    db.TxManager
+	publisher    Publisher
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      func(attempt int) time.Duration
+}
+
+// NewRelay создаёт релей. Обработка каждой пачки выполняется в транзакции txManager.
+func NewRelay(dbc db.DB, txManager db.TxManager, publisher Publisher, opts ...Option) *Relay {
+	r := &Relay{
+		db:           dbc,
+		txManager:    txManager,
+		publisher:    publisher,
+		batchSize:    defaultBatchSize,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithBatchSize задаёт максимальное число сообщений, обрабатываемых за одну транзакцию
+func WithBatchSize(n int) Option {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithPollInterval задаёт паузу между опросами, когда в outbox нет сообщений
+func WithPollInterval(d time.Duration) Option {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+// WithMaxAttempts задаёт число попыток доставки, после которого сообщение отправляется в dead letter
+func WithMaxAttempts(n int) Option {
+	return func(r *Relay) {
+		r.maxAttempts = n
+	}
+}
+
+// WithBackoff задаёт паузу перед повторной доставкой после attempt неудачных попыток
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(r *Relay) {
+		r.backoff = backoff
+	}
+}
+
+// Run обрабатывает outbox, пока не будет отменён контекст.
+// Если пачка была заполнена полностью, следующая обрабатывается без паузы.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.ProcessBatch(ctx)
+		if err != nil {
+			log.Println("outbox relay:", err)
+		}
+
+		if err == nil && n >= r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// ProcessBatch обрабатывает одну пачку сообщений и возвращает их количество
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	var processed int
+
+	err := r.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var msgs []Message
+
+		q := db.Query{
+			Name: "outbox.Fetch",
+			QueryRaw: `SELECT id, topic, key, payload, headers, attempts, created_at
+				FROM outbox
+				WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED`,
+		}
+
+		if err := r.db.ScanAllContext(ctx, &msgs, q, r.batchSize); err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			if err := r.deliver(ctx, msg); err != nil {
+				return err
+			}
+		}
+
+		processed = len(msgs)
+
+		return nil
+	})
+
+	return processed, err
+}
+
+// deliver публикует сообщение и фиксирует результат попытки
+func (r *Relay) deliver(ctx context.Context, msg Message) error {
+	errPublish := r.publisher.Publish(ctx, msg)
+	if errPublish == nil {
+		q := db.Query{
+			Name:     "outbox.MarkDelivered",
+			QueryRaw: "UPDATE outbox SET delivered_at = now(), attempts = attempts + 1 WHERE id = $1",
+		}
+
+		_, err := r.db.ExecContext(ctx, q, msg.ID)
+		return err
+	}
+
+	attempts := msg.Attempts + 1
+	if attempts >= r.maxAttempts {
+		log.Printf("outbox: message %d moved to dead letter after %d attempts: %v", msg.ID, attempts, errPublish)
+
+		q := db.Query{
+			Name:     "outbox.MarkDead",
+			QueryRaw: "UPDATE outbox SET dead_at = now(), attempts = $2, last_error = $3 WHERE id = $1",
+		}
+
+		_, err := r.db.ExecContext(ctx, q, msg.ID, attempts, errPublish.Error())
+		return err
+	}
+
+	q := db.Query{
+		Name:     "outbox.MarkFailed",
+		QueryRaw: "UPDATE outbox SET attempts = $2, last_error = $3, next_attempt_at = now() + $4::interval WHERE id = $1",
+	}
+
+	_, err := r.db.ExecContext(ctx, q, msg.ID, attempts, errPublish.Error(), r.backoff(attempts))
+	return err
+}
+
+// defaultBackoff экспоненциальная пауза от секунды до пяти минут
+func defaultBackoff(attempt int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempt && d < 5*time.Minute; i++ {
+		d *= 2
+	}
+
+	return min(d, 5*time.Minute)
+}
@@ -0,0 +1,165 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	dbMocks "github.com/ne4chelovek/chat_common/pkg/db/mocks"
+	"github.com/ne4chelovek/chat_common/pkg/db/outbox"
+	"github.com/ne4chelovek/chat_common/pkg/db/outbox/mocks"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+// execCall запрос, выполненный через fakeDB.ExecContext
+type execCall struct {
+	q    db.Query
+	args []interface{}
+}
+
+// fakeDB отдаёт заранее заданные сообщения и запоминает выполненные команды
+type fakeDB struct {
+	db.DB
+	msgs  []outbox.Message
+	execs []execCall
+}
+
+func (f *fakeDB) ScanAllContext(_ context.Context, dest interface{}, _ db.Query, _ ...interface{}) error {
+	*dest.(*[]outbox.Message) = f.msgs
+	return nil
+}
+
+func (f *fakeDB) ExecContext(_ context.Context, q db.Query, args ...interface{}) (pgconn.CommandTag, error) {
+	f.execs = append(f.execs, execCall{q: q, args: args})
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+// fakeTxManager выполняет обработчик без настоящей транзакции
+type fakeTxManager struct {
+	db.TxManager
+}
+
+func (fakeTxManager) ReadCommitted(ctx context.Context, f db.Handler) error {
+	return f(ctx)
+}
+
+func newTestRelay(t *testing.T, dbc *fakeDB, publisher outbox.Publisher) *outbox.Relay {
+	t.Helper()
+
+	return outbox.NewRelay(dbc, fakeTxManager{}, publisher,
+		outbox.WithMaxAttempts(3),
+		outbox.WithBackoff(func(attempt int) time.Duration { return time.Duration(attempt) * time.Minute }),
+	)
+}
+
+func TestProcessBatchMarksDelivered(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	msg := outbox.Message{ID: 7, Topic: "chat.created", Payload: []byte(`{}`)}
+	publisher := mocks.NewPublisherMock(mc).PublishMock.Expect(minimock.AnyContext, msg).Return(nil)
+	dbc := &fakeDB{msgs: []outbox.Message{msg}}
+
+	n, err := newTestRelay(t, dbc, publisher).ProcessBatch(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessBatch: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("processed = %d, want 1", n)
+	}
+
+	if len(dbc.execs) != 1 {
+		t.Fatalf("execs = %d, want 1", len(dbc.execs))
+	}
+	call := dbc.execs[0]
+	if call.q.Name != "outbox.MarkDelivered" || !strings.Contains(call.q.QueryRaw, "delivered_at = now()") {
+		t.Fatalf("unexpected query %s: %s", call.q.Name, call.q.QueryRaw)
+	}
+	if call.args[0] != int64(7) {
+		t.Fatalf("id = %v, want 7", call.args[0])
+	}
+}
+
+func TestProcessBatchSchedulesRetry(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	msg := outbox.Message{ID: 7, Topic: "chat.created", Attempts: 1}
+	publisher := mocks.NewPublisherMock(mc).PublishMock.Return(errors.New("broker unavailable"))
+	dbc := &fakeDB{msgs: []outbox.Message{msg}}
+
+	if _, err := newTestRelay(t, dbc, publisher).ProcessBatch(context.Background()); err != nil {
+		t.Fatalf("ProcessBatch: %v", err)
+	}
+
+	if len(dbc.execs) != 1 {
+		t.Fatalf("execs = %d, want 1", len(dbc.execs))
+	}
+	call := dbc.execs[0]
+	if call.q.Name != "outbox.MarkFailed" {
+		t.Fatalf("query = %s, want outbox.MarkFailed", call.q.Name)
+	}
+	for _, col := range []string{"attempts = $2", "last_error = $3", "next_attempt_at = now() + $4::interval"} {
+		if !strings.Contains(call.q.QueryRaw, col) {
+			t.Fatalf("query %q does not set %s", call.q.QueryRaw, col)
+		}
+	}
+	if call.args[1] != 2 || call.args[2] != "broker unavailable" || call.args[3] != 2*time.Minute {
+		t.Fatalf("args = %v, want [7 2 broker unavailable 2m0s]", call.args)
+	}
+}
+
+func TestProcessBatchMovesToDeadLetter(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	msg := outbox.Message{ID: 7, Topic: "chat.created", Attempts: 2}
+	publisher := mocks.NewPublisherMock(mc).PublishMock.Return(errors.New("broker unavailable"))
+	dbc := &fakeDB{msgs: []outbox.Message{msg}}
+
+	if _, err := newTestRelay(t, dbc, publisher).ProcessBatch(context.Background()); err != nil {
+		t.Fatalf("ProcessBatch: %v", err)
+	}
+
+	if len(dbc.execs) != 1 {
+		t.Fatalf("execs = %d, want 1", len(dbc.execs))
+	}
+	call := dbc.execs[0]
+	if call.q.Name != "outbox.MarkDead" || !strings.Contains(call.q.QueryRaw, "dead_at = now()") {
+		t.Fatalf("unexpected query %s: %s", call.q.Name, call.q.QueryRaw)
+	}
+	if call.args[1] != 3 || call.args[2] != "broker unavailable" {
+		t.Fatalf("args = %v, want [7 3 broker unavailable]", call.args)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	msg := outbox.Message{Topic: "chat.created", Key: "1", Payload: []byte(`{}`)}
+
+	t.Run("without transaction", func(t *testing.T) {
+		dbc := &fakeDB{}
+
+		err := outbox.Enqueue(context.Background(), dbc, msg)
+		if !errors.Is(err, outbox.ErrTxRequired) {
+			t.Fatalf("err = %v, want ErrTxRequired", err)
+		}
+		if len(dbc.execs) != 0 {
+			t.Fatalf("execs = %d, want 0", len(dbc.execs))
+		}
+	})
+
+	t.Run("inside transaction", func(t *testing.T) {
+		mc := minimock.NewController(t)
+		dbc := &fakeDB{}
+		ctx := pg.MakeContextTx(context.Background(), dbMocks.NewTxMock(mc))
+
+		if err := outbox.Enqueue(ctx, dbc, msg); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if len(dbc.execs) != 1 || dbc.execs[0].q.Name != "outbox.Enqueue" {
+			t.Fatalf("execs = %v, want single outbox.Enqueue", dbc.execs)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    topic           TEXT        NOT NULL,
+    key             TEXT        NOT NULL DEFAULT '',
+    payload         BYTEA       NOT NULL,
+    headers         JSONB       NOT NULL DEFAULT '{}',
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    dead_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+    ON outbox (next_attempt_at, id)
+    WHERE delivered_at IS NULL AND dead_at IS NULL;
@@ -0,0 +1,151 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor курсор повреждён, подделан или выдан для другого запроса
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// direction направление перехода по курсору
+type direction string
+
+const (
+	forward  direction = "n"
+	backward direction = "p"
+)
+
+// cursor содержимое курсора: направление и значения ключа сортировки граничной строки
+type cursor struct {
+	Dir    direction `json:"d"`
+	Values []value   `json:"v"`
+}
+
+// value значение ключа с типом, чтобы после декодирования оно передавалось в запрос
+// тем же типом Go, а не числом с плавающей точкой или строкой из JSON
+type value struct {
+	Type string `json:"t"`
+	V    string `json:"v"`
+}
+
+func encodeValue(v any) (value, error) {
+	// разыменовываем указатели, nil-указатель соответствует NULL
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return value{Type: "null"}, nil
+		}
+
+		return encodeValue(rv.Elem().Interface())
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return value{Type: "null"}, nil
+	case int:
+		return value{Type: "int", V: fmt.Sprint(x)}, nil
+	case int32:
+		return value{Type: "int", V: fmt.Sprint(x)}, nil
+	case int64:
+		return value{Type: "int", V: fmt.Sprint(x)}, nil
+	case float64:
+		return value{Type: "float", V: fmt.Sprint(x)}, nil
+	case string:
+		return value{Type: "string", V: x}, nil
+	case bool:
+		return value{Type: "bool", V: fmt.Sprint(x)}, nil
+	case time.Time:
+		return value{Type: "time", V: x.Format(time.RFC3339Nano)}, nil
+	case fmt.Stringer:
+		return value{Type: "string", V: x.String()}, nil
+	default:
+		return value{}, fmt.Errorf("pagination: unsupported sort key type %T", v)
+	}
+}
+
+func (v value) decode() (any, error) {
+	var (
+		out any
+		err error
+	)
+
+	switch v.Type {
+	case "null":
+		return nil, nil
+	case "int":
+		var i int64
+		_, err = fmt.Sscan(v.V, &i)
+		out = i
+	case "float":
+		var f float64
+		_, err = fmt.Sscan(v.V, &f)
+		out = f
+	case "string":
+		out = v.V
+	case "bool":
+		out = v.V == "true"
+	case "time":
+		out, err = time.Parse(time.RFC3339Nano, v.V)
+	default:
+		err = fmt.Errorf("unknown value type %q", v.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return out, nil
+}
+
+// sign кодирует курсор и подписывает его HMAC, привязывая к запросу scope
+func (p *Paginator) sign(scope string, c cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(p.mac(scope, payload)), nil
+}
+
+// verify проверяет подпись курсора и декодирует его
+func (p *Paginator) verify(scope string, token string) (cursor, error) {
+	var c cursor
+
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, p.mac(scope, payload)) {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(payload, &c); err != nil || (c.Dir != forward && c.Dir != backward) {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+func (p *Paginator) mac(scope string, payload []byte) []byte {
+	h := hmac.New(sha256.New, p.secret)
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(payload)
+
+	return h.Sum(nil)
+}
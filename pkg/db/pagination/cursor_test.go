@@ -0,0 +1,110 @@
+package pagination
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPaginator(t *testing.T, secret string) *Paginator {
+	t.Helper()
+
+	p, err := New([]byte(secret))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return p
+}
+
+func TestNewRequiresSecret(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Fatal("New(nil) must fail")
+	}
+	if _, err := New([]byte{}); err == nil {
+		t.Fatal("New(empty) must fail")
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	p := newTestPaginator(t, "secret")
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+
+	values := make([]value, 0, 4)
+	for _, v := range []any{int64(42), "chat", created, (*string)(nil)} {
+		encoded, err := encodeValue(v)
+		if err != nil {
+			t.Fatalf("encodeValue(%v): %v", v, err)
+		}
+		values = append(values, encoded)
+	}
+
+	token, err := p.sign("scope", cursor{Dir: backward, Values: values})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	c, err := p.verify("scope", token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if c.Dir != backward || len(c.Values) != len(values) {
+		t.Fatalf("cursor = %+v", c)
+	}
+
+	want := []any{int64(42), "chat", created, nil}
+	for i, v := range c.Values {
+		got, err := v.decode()
+		if err != nil {
+			t.Fatalf("decode %d: %v", i, err)
+		}
+		if tm, ok := got.(time.Time); ok {
+			if !tm.Equal(created) {
+				t.Fatalf("value %d = %v, want %v", i, got, created)
+			}
+			continue
+		}
+		if got != want[i] {
+			t.Fatalf("value %d = %#v, want %#v", i, got, want[i])
+		}
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	p := newTestPaginator(t, "secret")
+
+	token, err := p.sign("scope", cursor{Dir: forward, Values: []value{{Type: "int", V: "1"}}})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+
+	forged, err := p.sign("scope", cursor{Dir: forward, Values: []value{{Type: "int", V: "2"}}})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name  string
+		p     *Paginator
+		scope string
+		token string
+	}{
+		{name: "payload replaced", p: p, scope: "scope", token: forgedPayload + "." + sig},
+		{name: "signature truncated", p: p, scope: "scope", token: payload + "." + sig[:len(sig)-2]},
+		{name: "other scope", p: p, scope: "other", token: token},
+		{name: "other secret", p: newTestPaginator(t, "another"), scope: "scope", token: token},
+		{name: "no signature", p: p, scope: "scope", token: payload},
+		{name: "not base64", p: p, scope: "scope", token: "!!!." + sig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.p.verify(tt.scope, tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("verify = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+// defaultLimit размер страницы, если он не задан
+const defaultLimit = 20
+
+// Column колонка сортировки. Набор колонок должен однозначно упорядочивать строки,
+// поэтому последней обычно указывают первичный ключ. Колонки могут содержать NULL:
+// как и в ORDER BY по умолчанию, NULL считается больше любого значения.
+type Column struct {
+	Name string
+	Desc bool
+}
+
+// Request параметры запроса страницы
+type Request struct {
+	// Cursor курсор Page.Next или Page.Prev предыдущей страницы, пустой для первой страницы
+	Cursor string
+	// Limit размер страницы
+	Limit int
+}
+
+// Page страница результатов
+type Page[T any] struct {
+	Items []T
+	// Next курсор следующей страницы, пустой если это последняя страница
+	Next string
+	// Prev курсор предыдущей страницы, пустой если это первая страница
+	Prev string
+}
+
+// Paginator подписывает курсоры секретом, чтобы клиент не мог их подделать
+type Paginator struct {
+	secret []byte
+}
+
+// New создаёт пагинатор с секретом для подписи курсоров. Пустой секрет не допускается:
+// с ним подпись может посчитать кто угодно.
+func New(secret []byte) (*Paginator, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("pagination: secret must not be empty")
+	}
+
+	return &Paginator{secret: secret}, nil
+}
+
+// Fetch выполняет базовый запрос q как подзапрос, добавляя к нему условие по ключу сортировки
+// из курсора, ORDER BY по columns и LIMIT, и сканирует страницу в значения типа T.
+// Значения ключа берутся из полей T с тегами db, совпадающими с именами колонок.
+// Базовый запрос использует позиционные плейсхолдеры $1..$N для args.
+func Fetch[T any](ctx context.Context, dbc db.NamedExecer, p *Paginator, q db.Query, columns []Column, req Request, args ...any) (Page[T], error) {
+	var page Page[T]
+
+	if len(columns) == 0 {
+		return page, fmt.Errorf("pagination: at least one sort column is required")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	scope := scopeOf(q, columns)
+
+	c := cursor{Dir: forward}
+	if req.Cursor != "" {
+		var err error
+		if c, err = p.verify(scope, req.Cursor); err != nil {
+			return page, err
+		}
+		if len(c.Values) != len(columns) {
+			return page, ErrInvalidCursor
+		}
+	}
+
+	query, queryArgs, err := build(q, columns, c, limit, args)
+	if err != nil {
+		return page, err
+	}
+
+	var items []T
+	if err = dbc.ScanAllContext(ctx, &items, query, queryArgs...); err != nil {
+		return page, err
+	}
+
+	// одна лишняя строка показывает, есть ли данные дальше в направлении обхода
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	if c.Dir == backward {
+		slices.Reverse(items)
+	}
+
+	page.Items = items
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	hasNext := c.Dir == forward && more || c.Dir == backward
+	hasPrev := c.Dir == backward && more || c.Dir == forward && req.Cursor != ""
+
+	if hasNext {
+		if page.Next, err = p.cursorFor(scope, forward, items[len(items)-1], columns); err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = p.cursorFor(scope, backward, items[0], columns); err != nil {
+			return page, err
+		}
+	}
+
+	return page, nil
+}
+
+// build строит запрос страницы. Условие по ключу раскрывается в виде
+// (a > $1) OR (a = $1 AND b < $2) ..., что работает для колонок с разным направлением сортировки.
+// Для NULL в курсоре параметр не передаётся, а сравнения заменяются на IS NULL и IS NOT NULL.
+func build(q db.Query, columns []Column, c cursor, limit int, args []any) (db.Query, []any, error) {
+	queryArgs := append([]any(nil), args...)
+
+	var b strings.Builder
+	b.WriteString("SELECT * FROM (")
+	b.WriteString(q.QueryRaw)
+	b.WriteString(") AS page")
+
+	if len(c.Values) > 0 {
+		// пустой параметр означает, что значение ключа в курсоре NULL
+		params := make([]string, len(columns))
+		for i, v := range c.Values {
+			decoded, err := v.decode()
+			if err != nil {
+				return q, nil, err
+			}
+			if decoded == nil {
+				continue
+			}
+
+			queryArgs = append(queryArgs, decoded)
+			params[i] = fmt.Sprintf("$%d", len(queryArgs))
+		}
+
+		or := make([]string, len(columns))
+		for i, col := range columns {
+			and := make([]string, 0, i+1)
+			for j := 0; j < i; j++ {
+				and = append(and, equal(ident(columns[j]), params[j]))
+			}
+
+			greater := col.Desc != (c.Dir == forward)
+			and = append(and, beyond(ident(col), params[i], greater))
+
+			or[i] = "(" + strings.Join(and, " AND ") + ")"
+		}
+
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(or, " OR "))
+	}
+
+	order := make([]string, len(columns))
+	for i, col := range columns {
+		dir := "ASC"
+		if col.Desc == (c.Dir == forward) {
+			dir = "DESC"
+		}
+		order[i] = ident(col) + " " + dir
+	}
+
+	queryArgs = append(queryArgs, limit+1)
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT $%d", strings.Join(order, ", "), len(queryArgs))
+
+	return db.Query{Name: q.Name, QueryRaw: b.String(), MaxRows: q.MaxRows}, queryArgs, nil
+}
+
+// equal условие совпадения колонки со значением курсора
+func equal(col, param string) string {
+	if param == "" {
+		return col + " IS NULL"
+	}
+
+	return fmt.Sprintf("%s = %s", col, param)
+}
+
+// beyond условие того, что значение колонки больше значения курсора (greater) или меньше него.
+// NULL больше любого значения, как в ORDER BY по умолчанию, и ничто не больше NULL.
+func beyond(col, param string, greater bool) string {
+	switch {
+	case param == "" && greater:
+		return "FALSE"
+	case param == "":
+		return col + " IS NOT NULL"
+	case greater:
+		return fmt.Sprintf("(%s > %s OR %s IS NULL)", col, param, col)
+	default:
+		return fmt.Sprintf("%s < %s", col, param)
+	}
+}
+
+// cursorFor создаёт курсор из значений ключа сортировки строки item
+func (p *Paginator) cursorFor(scope string, dir direction, item any, columns []Column) (string, error) {
+	fields, err := db.Named(item).Args()
+	if err != nil {
+		return "", err
+	}
+
+	c := cursor{Dir: dir, Values: make([]value, len(columns))}
+	for i, col := range columns {
+		v, ok := fields[col.Name]
+		if !ok {
+			return "", fmt.Errorf("pagination: result type has no field for column %q", col.Name)
+		}
+
+		if c.Values[i], err = encodeValue(v); err != nil {
+			return "", err
+		}
+	}
+
+	return p.sign(scope, c)
+}
+
+// scopeOf привязывает курсор к запросу и колонкам сортировки, чтобы курсор
+// одного списка нельзя было подставить в другой
+func scopeOf(q db.Query, columns []Column) string {
+	parts := []string{q.Label()}
+	for _, col := range columns {
+		parts = append(parts, fmt.Sprintf("%s:%t", col.Name, col.Desc))
+	}
+
+	return strings.Join(parts, "|")
+}
+
+func ident(col Column) string {
+	return "page." + pgx.Identifier{col.Name}.Sanitize()
+}
@@ -0,0 +1,103 @@
+package pagination
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+func TestBuild(t *testing.T) {
+	q := db.Query{Name: "chat.List", QueryRaw: "SELECT id, created_at FROM chats WHERE owner_id = $1"}
+	columns := []Column{{Name: "created_at", Desc: true}, {Name: "id"}}
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keys := []value{{Type: "time", V: created.Format(time.RFC3339Nano)}, {Type: "int", V: "5"}}
+
+	const base = `SELECT * FROM (SELECT id, created_at FROM chats WHERE owner_id = $1) AS page`
+
+	tests := []struct {
+		name     string
+		cursor   cursor
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "first page",
+			cursor:   cursor{Dir: forward},
+			wantSQL:  base + ` ORDER BY page."created_at" DESC, page."id" ASC LIMIT $2`,
+			wantArgs: []any{42, 11},
+		},
+		{
+			name:   "forward",
+			cursor: cursor{Dir: forward, Values: keys},
+			wantSQL: base + ` WHERE (page."created_at" < $2)` +
+				` OR (page."created_at" = $2 AND (page."id" > $3 OR page."id" IS NULL))` +
+				` ORDER BY page."created_at" DESC, page."id" ASC LIMIT $4`,
+			wantArgs: []any{42, created, int64(5), 11},
+		},
+		{
+			name:   "backward",
+			cursor: cursor{Dir: backward, Values: keys},
+			wantSQL: base + ` WHERE ((page."created_at" > $2 OR page."created_at" IS NULL))` +
+				` OR (page."created_at" = $2 AND page."id" < $3)` +
+				` ORDER BY page."created_at" ASC, page."id" DESC LIMIT $4`,
+			wantArgs: []any{42, created, int64(5), 11},
+		},
+		{
+			name:   "forward from NULL",
+			cursor: cursor{Dir: forward, Values: []value{{Type: "null"}, {Type: "int", V: "5"}}},
+			wantSQL: base + ` WHERE (page."created_at" IS NOT NULL)` +
+				` OR (page."created_at" IS NULL AND (page."id" > $2 OR page."id" IS NULL))` +
+				` ORDER BY page."created_at" DESC, page."id" ASC LIMIT $3`,
+			wantArgs: []any{42, int64(5), 11},
+		},
+		{
+			name:   "backward from NULL",
+			cursor: cursor{Dir: backward, Values: []value{{Type: "null"}, {Type: "int", V: "5"}}},
+			wantSQL: base + ` WHERE (FALSE)` +
+				` OR (page."created_at" IS NULL AND page."id" < $2)` +
+				` ORDER BY page."created_at" ASC, page."id" DESC LIMIT $3`,
+			wantArgs: []any{42, int64(5), 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := build(q, columns, tt.cursor, 10, []any{42})
+			if err != nil {
+				t.Fatalf("build: %v", err)
+			}
+
+			if got.QueryRaw != tt.wantSQL {
+				t.Errorf("sql =\n%s\nwant\n%s", got.QueryRaw, tt.wantSQL)
+			}
+			if got.Name != q.Name {
+				t.Errorf("name = %q, want %q", got.Name, q.Name)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestScopeBindsQueryAndColumns(t *testing.T) {
+	q := db.Query{Name: "chat.List", QueryRaw: "SELECT 1"}
+	columns := []Column{{Name: "id"}}
+
+	scopes := map[string]string{
+		"base":       scopeOf(q, columns),
+		"query":      scopeOf(db.Query{Name: "message.List", QueryRaw: "SELECT 1"}, columns),
+		"direction":  scopeOf(q, []Column{{Name: "id", Desc: true}}),
+		"column set": scopeOf(q, []Column{{Name: "created_at"}, {Name: "id"}}),
+	}
+
+	seen := make(map[string]string)
+	for name, scope := range scopes {
+		if other, ok := seen[scope]; ok {
+			t.Fatalf("scopes %q and %q are equal: %s", name, other, scope)
+		}
+		seen[scope] = name
+	}
+}
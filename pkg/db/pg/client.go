@@ -9,15 +9,17 @@ import (
 )
 
 // pgClient - структура, реализующая интерфейс db.Client.
-// Содержит masterDBC, который представляет собой соединение с базой данных.
+// Содержит masterDBC, который представляет собой соединение с базой данных,
+// и replicaDBC, который выполняет запросы на репликах.
 type pgClient struct {
-	masterDBC db.DB // Интерфейс для работы с базой данных
+	masterDBC  db.DB // Интерфейс для работы с базой данных
+	replicaDBC db.DB // Интерфейс для чтения с реплик, при их отсутствии - с мастера
 }
 
 // New - функция-конструктор для создания нового клиента базы данных.
-// Принимает контекст (ctx) и строку подключения (dsn).
+// Принимает контекст (ctx), строку подключения (dsn) и опции DB (opts).
 // Возвращает интерфейс db.Client и ошибку, если что-то пошло не так.
-func New(ctx context.Context, dsn string) (db.Client, error) {
+func New(ctx context.Context, dsn string, opts ...Option) (db.Client, error) {
 	// Создаем пул соединений с базой данных PostgreSQL
 	dbc, err := pgxpool.New(ctx, dsn)
 	if err != nil {
@@ -25,10 +27,19 @@ func New(ctx context.Context, dsn string) (db.Client, error) {
 		return nil, fmt.Errorf("failed to connect to db: %v", err)
 	}
 
+	// Подключаемся к репликам, если они заданы через WithReplicas
+	p := newDB(dbc, opts...)
+	if err = p.connectReplicas(ctx); err != nil {
+		dbc.Close()
+		return nil, err
+	}
+	p.initReplicas()
+
 	// Возвращаем новый экземпляр pgClient, где masterDBC инициализирован
 	// с использованием структуры pg, которая реализует интерфейс db.DB.
 	return &pgClient{
-		masterDBC: &pg{dbc: dbc},
+		masterDBC:  p,
+		replicaDBC: p.replicaView(),
 	}, nil
 }
 
@@ -38,6 +49,13 @@ func (c *pgClient) DB() db.DB {
 	return c.masterDBC
 }
 
+// Replica - метод, возвращающий интерфейс db.DB, который выполняет чтения вне транзакции
+// и транзакции только для чтения на репликах, а записи и остальные транзакции на мастере.
+// Если здоровых реплик нет, запросы выполняются на мастере.
+func (c *pgClient) Replica() db.DB {
+	return c.replicaDBC
+}
+
 // Close - метод для закрытия соединения с базой данных.
 // Если masterDBC не равен nil, вызывается метод Close() для закрытия соединения.
 // Возвращает ошибку, если что-то пошло не так.
@@ -0,0 +1,253 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// consistencyPollInterval период повторной проверки реплик при ожидании
+const consistencyPollInterval = 10 * time.Millisecond
+
+// LSN позиция в журнале предзаписи (WAL)
+type LSN uint64
+
+// ParseLSN разбирает LSN в текстовом формате Postgres, например 16/B374D848
+func ParseLSN(s string) (LSN, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("invalid lsn %q: %w", s, err)
+	}
+
+	return LSN(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+type consistencyKey struct{}
+
+// consistency позиция последней записи на мастере, видимая в рамках одного запроса пользователя
+type consistency struct {
+	mu  sync.Mutex
+	lsn LSN
+}
+
+func (c *consistency) advance(lsn LSN) {
+	c.mu.Lock()
+	if lsn > c.lsn {
+		c.lsn = lsn
+	}
+	c.mu.Unlock()
+}
+
+func (c *consistency) get() LSN {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lsn
+}
+
+// TrackWrites включает для контекста чтение собственных записей: после записей на мастере
+// в контексте запоминается LSN мастера, и последующие чтения идут только на реплики, которые его догнали
+func TrackWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(consistencyKey{}).(*consistency); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, consistencyKey{}, &consistency{})
+}
+
+// ContextWithToken включает чтение собственных записей, начиная с позиции из токена,
+// полученного от ConsistencyToken, например в другом запросе того же пользователя
+func ContextWithToken(ctx context.Context, token string) (context.Context, error) {
+	lsn, err := ParseLSN(token)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = TrackWrites(ctx)
+	ctx.Value(consistencyKey{}).(*consistency).advance(lsn)
+
+	return ctx, nil
+}
+
+// ConsistencyToken возвращает токен с позицией последней записи из контекста,
+// который можно передать клиенту и восстановить через ContextWithToken. Пустая строка, если записей не было.
+func ConsistencyToken(ctx context.Context) string {
+	if lsn := minLSN(ctx); lsn > 0 {
+		return lsn.String()
+	}
+
+	return ""
+}
+
+// WithReadYourWrites задаёт, сколько чтение может ждать, пока реплика догонит позицию из контекста,
+// прежде чем уйти на мастер. Ноль означает переход на мастер без ожидания.
+func WithReadYourWrites(maxWait time.Duration) Option {
+	return func(p *pg) {
+		p.consistencyWait = maxWait
+	}
+}
+
+// minLSN возвращает позицию, которую должна догнать реплика для чтения с этим контекстом
+func minLSN(ctx context.Context) LSN {
+	c, ok := ctx.Value(consistencyKey{}).(*consistency)
+	if !ok {
+		return 0
+	}
+
+	return c.get()
+}
+
+// recordWrite запоминает текущий LSN мастера после записи, если контекст отслеживает записи
+func (p *pg) recordWrite(ctx context.Context) {
+	c, ok := ctx.Value(consistencyKey{}).(*consistency)
+	if !ok || p.replicas == nil {
+		return
+	}
+
+	var s string
+	if err := p.dbc.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&s); err != nil {
+		// позиция записи неизвестна: максимальный LSN не догонит ни одна реплика,
+		// и чтения в рамках запроса уйдут на мастер
+		c.advance(^LSN(0))
+		return
+	}
+
+	if lsn, err := ParseLSN(s); err == nil {
+		c.advance(lsn)
+	}
+}
+
+// tracksWrites сообщает, нужно ли запомнить LSN мастера после запроса вне транзакции через pool
+func (p *pg) tracksWrites(ctx context.Context, pool *pgxpool.Pool) bool {
+	_, ok := ctx.Value(consistencyKey{}).(*consistency)
+	return ok && p.replicas != nil && pool == p.dbc
+}
+
+// recordRows вызывает record, когда выборка на мастере успешно прочитана до конца или закрыта
+type recordRows struct {
+	pgx.Rows
+	record func()
+	done   bool
+}
+
+func (r *recordRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.finish()
+
+	return false
+}
+
+func (r *recordRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *recordRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	if r.Rows.Err() == nil {
+		r.record()
+	}
+}
+
+// recordRow вызывает record после успешного чтения строки на мастере
+type recordRow struct {
+	pgx.Row
+	record func()
+}
+
+func (r *recordRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if err == nil {
+		r.record()
+	}
+
+	return err
+}
+
+// trackedTx запоминает LSN мастера после успешного коммита транзакции
+type trackedTx struct {
+	pgx.Tx
+	p *pg
+}
+
+func (t *trackedTx) Commit(ctx context.Context) error {
+	if err := t.Tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	t.p.recordWrite(ctx)
+
+	return nil
+}
+
+// track оборачивает пишущую транзакцию на мастере, если контекст отслеживает записи
+func (p *pg) track(ctx context.Context, tx pgx.Tx, pool *pgxpool.Pool, opts pgx.TxOptions) pgx.Tx {
+	if _, ok := ctx.Value(consistencyKey{}).(*consistency); !ok || pool != p.dbc || opts.AccessMode == pgx.ReadOnly {
+		return tx
+	}
+
+	return &trackedTx{Tx: tx, p: p}
+}
+
+// replayLSN запрашивает позицию, до которой реплика применила журнал
+func (r *replica) replayLSN(ctx context.Context) (LSN, error) {
+	var s *string
+	if err := r.pool.QueryRow(ctx, "SELECT pg_last_wal_replay_lsn()::text").Scan(&s); err != nil {
+		return 0, err
+	}
+	if s == nil {
+		return 0, fmt.Errorf("not a replica")
+	}
+
+	return ParseLSN(*s)
+}
+
+// caughtUp возвращает здоровые реплики, которые догнали позицию lsn. Если по данным последней
+// проверки таких нет, позиции реплик запрашиваются заново, пока не истечёт wait.
+func (s *replicaSet) caughtUp(ctx context.Context, healthy []*replica, lsn LSN, wait time.Duration) []*replica {
+	deadline := time.Now().Add(wait)
+
+	for refreshed := false; ; refreshed = true {
+		if refreshed {
+			for _, r := range healthy {
+				if replay, err := r.replayLSN(ctx); err == nil {
+					r.replay.Store(uint64(replay))
+				}
+			}
+		}
+
+		ready := make([]*replica, 0, len(healthy))
+		for _, r := range healthy {
+			if LSN(r.replay.Load()) >= lsn {
+				ready = append(ready, r)
+			}
+		}
+
+		if len(ready) > 0 || refreshed && !time.Now().Before(deadline) {
+			return ready
+		}
+
+		if refreshed {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(consistencyPollInterval):
+			}
+		}
+	}
+}
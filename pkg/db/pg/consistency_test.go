@@ -0,0 +1,113 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// fakeRow строка, чтение которой возвращает err
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(...any) error {
+	return r.err
+}
+
+func TestRecordRowsAfterIteration(t *testing.T) {
+	recorded := 0
+	rows := &recordRows{Rows: &fakeRows{n: 2}, record: func() { recorded++ }}
+
+	for rows.Next() {
+		if recorded != 0 {
+			t.Fatal("position recorded before the statement finished")
+		}
+	}
+	rows.Close()
+
+	if recorded != 1 {
+		t.Fatalf("recorded %d times, want 1", recorded)
+	}
+}
+
+func TestRecordRowsSkipsFailedStatement(t *testing.T) {
+	recorded := 0
+	rows := &recordRows{Rows: &fakeRows{err: errors.New("unique violation")}, record: func() { recorded++ }}
+
+	for rows.Next() {
+	}
+	rows.Close()
+
+	if recorded != 0 {
+		t.Fatalf("recorded %d times after failed statement, want 0", recorded)
+	}
+}
+
+func TestRecordRow(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", want: 1},
+		{name: "no rows", err: pgx.ErrNoRows},
+		{name: "error", err: errors.New("unique violation")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded := 0
+			r := &recordRow{Row: fakeRow{err: tt.err}, record: func() { recorded++ }}
+
+			if err := r.Scan(); !errors.Is(err, tt.err) {
+				t.Fatalf("Scan = %v, want %v", err, tt.err)
+			}
+			if recorded != tt.want {
+				t.Fatalf("recorded %d times, want %d", recorded, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracksWrites(t *testing.T) {
+	// пулы не подключаются к базе до первого запроса
+	master, err := pgxpool.New(context.Background(), "postgres://localhost/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	replicaPool, err := pgxpool.New(context.Background(), "postgres://localhost/replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaPool.Close()
+
+	p := newDB(master, WithReplicaPools(replicaPool))
+	p.initReplicas()
+
+	tracked := TrackWrites(context.Background())
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		pool *pgxpool.Pool
+		want bool
+	}{
+		{name: "master", ctx: tracked, pool: master, want: true},
+		{name: "replica", ctx: tracked, pool: replicaPool},
+		{name: "untracked context", ctx: context.Background(), pool: master},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.tracksWrites(tt.ctx, tt.pool); got != tt.want {
+				t.Fatalf("tracksWrites = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+// replicationLagQuery возвращает, получает ли реплика журнал от мастера, и её отставание в секундах.
+// Если реплика применила всё, что получила, и приёмник журнала подключён, отставание считается
+// нулевым: иначе на простаивающем мастере время последней применённой транзакции растёт бесконечно.
+// Реплика с отключившимся приёмником тоже применяет всё полученное, поэтому без проверки
+// pg_stat_wal_receiver она выглядела бы не отстающей, хотя данные на ней больше не обновляются.
+const replicationLagQuery = `WITH receiver AS (
+	SELECT EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming') AS streaming
+)
+SELECT streaming, CASE
+	WHEN streaming AND pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END
+FROM receiver`
+
+// HealthCheck параметры фоновой проверки реплик
+type HealthCheck struct {
+	// Interval период проверки
+	Interval time.Duration
+	// Timeout таймаут одной проверки, по умолчанию равен Interval
+	Timeout time.Duration
+	// MaxLag максимально допустимое отставание реплики. Ноль отключает проверку отставания.
+	MaxLag time.Duration
+}
+
+// replicaState результат последней проверки реплики
+type replicaState struct {
+	mu        sync.Mutex
+	lag       time.Duration
+	err       error
+	checkedAt time.Time
+}
+
+// WithHealthCheck включает фоновую проверку реплик. Реплика, которая не отвечает на ping,
+// не получает журнал от мастера или отстаёт больше MaxLag, исключается из балансировки и возвращается после успешной проверки.
+// Если подходящих реплик нет, чтения выполняются на мастере.
+func WithHealthCheck(hc HealthCheck) Option {
+	return func(p *pg) {
+		p.healthCheck = hc
+	}
+}
+
+// startHealthCheck запускает проверку реплик, которая работает до закрытия набора
+func (s *replicaSet) startHealthCheck(hc HealthCheck) {
+	if hc.Interval <= 0 {
+		return
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = hc.Interval
+	}
+
+	s.stop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(hc.Interval)
+		defer ticker.Stop()
+
+		for {
+			s.checkAll(hc)
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkAll проверяет все реплики параллельно
+func (s *replicaSet) checkAll(hc HealthCheck) {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.check(hc)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// check проверяет доступность и отставание реплики и обновляет её состояние
+func (r *replica) check(hc HealthCheck) {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.Timeout)
+	defer cancel()
+
+	var (
+		lag time.Duration
+		err = r.pool.Ping(ctx)
+	)
+
+	if err == nil {
+		var (
+			streaming bool
+			seconds   float64
+		)
+		if err = r.pool.QueryRow(ctx, replicationLagQuery).Scan(&streaming, &seconds); err == nil {
+			lag = time.Duration(seconds * float64(time.Second))
+			switch {
+			case !streaming:
+				err = fmt.Errorf("wal receiver is not streaming, replica is %v behind", lag)
+			case hc.MaxLag > 0 && lag > hc.MaxLag:
+				err = fmt.Errorf("replication lag %v exceeds %v", lag, hc.MaxLag)
+			}
+		}
+	}
+
+	if err == nil {
+		if replay, errReplay := r.replayLSN(ctx); errReplay == nil {
+			r.replay.Store(uint64(replay))
+		}
+	}
+
+	r.state.mu.Lock()
+	r.state.lag = lag
+	r.state.err = err
+	r.state.checkedAt = time.Now()
+	r.state.mu.Unlock()
+
+	r.healthy.Store(err == nil)
+}
+
+// status возвращает текущее состояние реплик
+func (s *replicaSet) status() []db.ReplicaStatus {
+	statuses := make([]db.ReplicaStatus, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		r.state.mu.Lock()
+		statuses = append(statuses, db.ReplicaStatus{
+			Host:      r.pool.Config().ConnConfig.Host,
+			Healthy:   r.healthy.Load(),
+			Lag:       r.state.lag,
+			Err:       r.state.err,
+			CheckedAt: r.state.checkedAt,
+		})
+		r.state.mu.Unlock()
+	}
+
+	return statuses
+}
+
+// ReplicaStatus - метод, возвращающий состояние реплик по результатам последней проверки
+func (c *pgClient) ReplicaStatus() []db.ReplicaStatus {
+	p, ok := c.masterDBC.(*pg)
+	if !ok || p.replicas == nil {
+		return nil
+	}
+
+	return p.replicas.status()
+}
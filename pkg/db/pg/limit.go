@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+// defaultRowWarnRatio доля от лимита, начиная с которой выборка считается близкой к нему
+const defaultRowWarnRatio = 0.8
+
+// RowLimitWarning вызывается, когда выборка ScanAllContext приблизилась к лимиту строк
+type RowLimitWarning func(ctx context.Context, q db.Query, rows, limit int)
+
+// WithMaxRows задаёт лимит строк для ScanAllContext по умолчанию. При превышении сканирование
+// прекращается и возвращается *db.RowLimitError. Лимит запроса задаётся в db.Query.MaxRows.
+func WithMaxRows(limit int) Option {
+	return func(p *pg) {
+		p.maxRows = limit
+	}
+}
+
+// WithRowLimitWarning задаёт долю лимита (например 0.8), начиная с которой вызывается warn.
+// По умолчанию предупреждение пишется в лог.
+func WithRowLimitWarning(ratio float64, warn RowLimitWarning) Option {
+	return func(p *pg) {
+		p.rowWarnRatio = ratio
+		p.rowWarn = warn
+	}
+}
+
+// rowLimit возвращает лимит строк для запроса, ноль означает отсутствие лимита
+func (p *pg) rowLimit(q db.Query) int {
+	switch {
+	case q.MaxRows < 0:
+		return 0
+	case q.MaxRows > 0:
+		return q.MaxRows
+	default:
+		return p.maxRows
+	}
+}
+
+// warnRowLimit сообщает о выборке, приблизившейся к лимиту
+func (p *pg) warnRowLimit(ctx context.Context, q db.Query, rows, limit int) {
+	ratio := p.rowWarnRatio
+	if ratio <= 0 {
+		ratio = defaultRowWarnRatio
+	}
+
+	if float64(rows) < ratio*float64(limit) {
+		return
+	}
+
+	if p.rowWarn != nil {
+		p.rowWarn(ctx, q, rows, limit)
+		return
+	}
+
+	log.Printf("warning: sql %v returned %d rows, limit is %d", q.Label(), rows, limit)
+}
+
+// limitRows прекращает чтение выборки после limit строк и сообщает об этом через Err.
+// При превышении лимита вызывается cancel, отменяющий контекст запроса.
+type limitRows struct {
+	pgx.Rows
+	limit    int
+	count    int
+	exceeded bool
+	cancel   context.CancelFunc
+}
+
+func (r *limitRows) Next() bool {
+	if r.exceeded || !r.Rows.Next() {
+		return false
+	}
+
+	r.count++
+	if r.count > r.limit {
+		r.exceeded = true
+		r.cancel()
+		return false
+	}
+
+	return true
+}
+
+func (r *limitRows) Err() error {
+	if r.exceeded {
+		return &db.RowLimitError{Limit: r.limit}
+	}
+
+	return r.Rows.Err()
+}
@@ -0,0 +1,48 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+func TestLimitRowsCancelsQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows := &limitRows{Rows: &fakeRows{n: 5}, limit: 2, cancel: cancel}
+
+	read := 0
+	for rows.Next() {
+		read++
+	}
+	if read != 2 {
+		t.Fatalf("read %d rows, want 2", read)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("query context is not cancelled after the limit was exceeded")
+	}
+
+	var limitErr *db.RowLimitError
+	if !errors.As(rows.Err(), &limitErr) || limitErr.Limit != 2 {
+		t.Fatalf("Err = %v, want row limit 2", rows.Err())
+	}
+}
+
+func TestLimitRowsWithinLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows := &limitRows{Rows: &fakeRows{n: 2}, limit: 2, cancel: cancel}
+	for rows.Next() {
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("query context cancelled although the limit was not exceeded")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -20,91 +21,252 @@ const (
 )
 
 type pg struct {
-	dbc *pgxpool.Pool
+	dbc          *pgxpool.Pool
+	session      SessionSettings
+	tenant       *TenantRouting
+	replicaDSNs  []string
+	replicaPools []*pgxpool.Pool
+	balancer     Balancer
+	replicas     *replicaSet
+	replicaOnly  bool
+	readRouting  bool
+	healthCheck  HealthCheck
+	// consistencyWait время ожидания реплики при чтении собственных записей
+	consistencyWait time.Duration
+	redaction       Redaction
+	maxRows         int
+	rowWarnRatio    float64
+	rowWarn         RowLimitWarning
 }
 
-func NewDB(dbc *pgxpool.Pool) db.DB {
-	return &pg{
-		dbc: dbc,
+func NewDB(dbc *pgxpool.Pool, opts ...Option) db.DB {
+	p := newDB(dbc, opts...)
+	p.initReplicas()
+
+	return p
+}
+
+func newDB(dbc *pgxpool.Pool, opts ...Option) *pg {
+	p := &pg{
+		dbc:       dbc,
+		redaction: defaultRedaction,
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
 func (p *pg) ScanOneContext(ctx context.Context, dest interface{}, q db.Query, args ...interface{}) error {
-	logQuery(ctx, q, args...)
+	p.logQuery(ctx, q, args...)
 
 	row, err := p.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
 	}
-	return pgxscan.ScanOne(dest, row)
+	return db.WrapError(q.Name, pgxscan.ScanOne(dest, row))
 }
 
 func (p *pg) ScanAllContext(ctx context.Context, dest interface{}, q db.Query, args ...interface{}) error {
-	logQuery(ctx, q, args...)
+	p.logQuery(ctx, q, args...)
 
-	rows, err := p.QueryContext(ctx, q, args...)
+	limit := p.rowLimit(q)
+	if limit <= 0 {
+		rows, err := p.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+
+		return db.WrapError(q.Name, pgxscan.ScanAll(dest, rows))
+	}
+
+	// при превышении лимита запрос вне транзакции отменяется, чтобы pgx не дочитывал оставшиеся
+	// строки при закрытии выборки. Внутри транзакции отмена закрыла бы соединение вместе с ней.
+	queryCtx, cancel := ctx, context.CancelFunc(func() {})
+	if _, ok := ctx.Value(TxKey).(pgx.Tx); !ok {
+		queryCtx, cancel = context.WithCancel(ctx)
+		defer cancel()
+	}
+
+	rows, err := p.QueryContext(queryCtx, q, args...)
 	if err != nil {
 		return err
 	}
 
-	return pgxscan.ScanAll(dest, rows)
+	// ограничиваем число строк, чтобы запрос без LIMIT не загрузил в память всю таблицу
+	limited := &limitRows{Rows: rows, limit: limit, cancel: cancel}
+	if err = pgxscan.ScanAll(dest, limited); err != nil {
+		return db.WrapError(q.Name, err)
+	}
+
+	p.warnRowLimit(ctx, q, limited.count, limit)
+
+	return nil
 }
 
 func (p *pg) ExecContext(ctx context.Context, q db.Query, args ...interface{}) (pgconn.CommandTag, error) {
-	logQuery(ctx, q, args...)
+	p.logQuery(ctx, q, args...)
+	args = prettier.Unwrap(args)
+
+	var (
+		tag pgconn.CommandTag
+		err error
+	)
 
 	tx, ok := ctx.Value(TxKey).(pgx.Tx)
 	if ok {
-		return tx.Exec(ctx, q.QueryRaw, args...)
+		tag, err = tx.Exec(ctx, q.QueryRaw, args...)
+	} else if tx, err = p.implicitTx(ctx, p.pool(ctx, true)); err == nil && tx != nil {
+		tag, err = execImplicit(ctx, tx, q.QueryRaw, args...)
+	} else if err == nil {
+		tag, err = p.pool(ctx, true).Exec(ctx, q.QueryRaw, args...)
+	}
+
+	// после записи вне транзакции запоминаем позицию мастера для чтения собственных записей
+	if !ok && err == nil {
+		p.recordWrite(ctx)
 	}
 
-	return p.dbc.Exec(ctx, q.QueryRaw, args...)
+	return tag, db.WrapError(q.Name, err)
 }
 
 func (p *pg) QueryContext(ctx context.Context, q db.Query, args ...interface{}) (pgx.Rows, error) {
-	logQuery(ctx, q, args...)
+	p.logQuery(ctx, q, args...)
+	args = prettier.Unwrap(args)
 
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
-	if ok {
-		return tx.Query(ctx, q.QueryRaw, args...)
+	var (
+		rows pgx.Rows
+		err  error
+	)
+
+	if tx, ok := ctx.Value(TxKey).(pgx.Tx); ok {
+		rows, err = tx.Query(ctx, q.QueryRaw, args...)
+		return rows, db.WrapError(q.Name, err)
+	}
+
+	pool := p.pool(ctx, false)
+	tx, err := p.implicitTx(ctx, pool)
+	if err != nil {
+		return nil, db.WrapError(q.Name, err)
+	}
+
+	if tx != nil {
+		if rows, err = tx.Query(ctx, q.QueryRaw, args...); err != nil {
+			_ = finishTx(ctx, tx, err)
+		} else {
+			rows = &txRows{Rows: rows, ctx: ctx, tx: tx}
+		}
+	} else {
+		rows, err = pool.Query(ctx, q.QueryRaw, args...)
+	}
+
+	// запрос на мастере может писать, например INSERT ... RETURNING, поэтому после него запоминаем позицию мастера
+	if err == nil && p.tracksWrites(ctx, pool) {
+		rows = &recordRows{Rows: rows, record: func() { p.recordWrite(ctx) }}
 	}
 
-	return p.dbc.Query(ctx, q.QueryRaw, args...)
+	return rows, db.WrapError(q.Name, err)
 }
 
+func (p *pg) QueryRowContext(ctx context.Context, q db.Query, args ...interface{}) pgx.Row {
+	p.logQuery(ctx, q, args...)
+	args = prettier.Unwrap(args)
 
-func (p *pg) QueryRowContext(ctx context.Context, q db.Query, args ...interface{}) pgx.Row{
-	logQuery(ctx, q, args...)
+	if tx, ok := ctx.Value(TxKey).(pgx.Tx); ok {
+		return &row{row: tx.QueryRow(ctx, q.QueryRaw, args...), name: q.Name}
+	}
 
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
-	if ok {
-		return tx.QueryRow(ctx, q.QueryRaw, args...)
+	pool := p.pool(ctx, false)
+	tx, err := p.implicitTx(ctx, pool)
+	if err != nil {
+		return &row{row: errRow{err: err}, name: q.Name}
+	}
+
+	var r pgx.Row
+	if tx != nil {
+		r = &txRow{Row: tx.QueryRow(ctx, q.QueryRaw, args...), ctx: ctx, tx: tx}
+	} else {
+		r = pool.QueryRow(ctx, q.QueryRaw, args...)
+	}
+
+	if p.tracksWrites(ctx, pool) {
+		r = &recordRow{Row: r, record: func() { p.recordWrite(ctx) }}
 	}
 
-	return p.dbc.QueryRow(ctx, q.QueryRaw, args...)
+	return &row{row: r, name: q.Name}
 }
 
+// BeginTx начинает транзакцию и применяет к ней настройки сессии и search_path тенанта из контекста
 func (p *pg) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	return p.dbc.BeginTx(ctx, txOptions)
+	values, err := p.settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// через DB реплик на реплике выполняются только транзакции только для чтения
+	pool := p.pool(ctx, !p.replicaOnly || txOptions.AccessMode != pgx.ReadOnly)
+
+	tx, err := p.beginPrepared(ctx, pool, txOptions, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.track(ctx, tx, pool, txOptions), nil
 }
 
 func (p *pg) Ping(ctx context.Context) error {
-	return p.dbc.Ping(ctx)
+	return p.pool(ctx, true).Ping(ctx)
 }
 
 func (p *pg) Close() {
+	// пулы DB реплик закрывает DB мастера
+	if p.replicaOnly {
+		return
+	}
+
 	p.dbc.Close()
+
+	if p.replicas != nil {
+		p.replicas.close()
+	}
+}
+
+// row оборачивает pgx.Row, чтобы ошибки Scan классифицировались так же, как и у остальных методов
+type row struct {
+	row  pgx.Row
+	name string
+}
+
+func (r *row) Scan(dest ...any) error {
+	return db.WrapError(r.name, r.row.Scan(dest...))
+}
+
+// errRow строка, чтение которой возвращает ошибку, возникшую до выполнения запроса
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
 }
 
 func MakeContextTx(ctx context.Context, tx pgx.Tx) context.Context {
 	return context.WithValue(ctx, TxKey, tx)
 }
 
-func logQuery(ctx context.Context, q db.Query, args ...interface{}) {
-	prettyQuery := prettier.Pretty(q.QueryRaw, prettier.PlaceholderDollar, args...)
+func (p *pg) logQuery(ctx context.Context, q db.Query, args ...interface{}) {
+	placeholder := prettier.PlaceholderDollar
+	if named, ok := db.NamedValues(args); ok {
+		placeholder = prettier.PlaceholderNamed
+		args = []any{named}
+	}
+
+	prettyQuery := prettier.PrettyRedacted(q.QueryRaw, placeholder, p.redaction.forQuery(q.Name), args...)
 	log.Println(
 		ctx,
-		fmt.Sprintf("sql: %v", q.Name),
+		fmt.Sprintf("sql: %v", q.Label()),
 		fmt.Sprintf("query: %v", prettyQuery),
 	)
 }
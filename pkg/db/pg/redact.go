@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"github.com/ne4chelovek/chat_common/pkg/db/prettier"
+)
+
+// defaultMaxLogValueLen длина, до которой по умолчанию усекаются строковые и бинарные значения в логе
+const defaultMaxLogValueLen = 1024
+
+// Redaction правила скрытия аргументов в логе запросов
+type Redaction struct {
+	// Redaction правила для всех запросов
+	prettier.Redaction
+	// Queries дополнительные правила по имени запроса (db.Query.Name)
+	Queries map[string]prettier.Redaction
+}
+
+// defaultRedaction скрывает значения колонок из prettier.SensitiveColumns и усекает длинные значения
+var defaultRedaction = Redaction{
+	Redaction: prettier.Redaction{
+		Columns: prettier.SensitiveColumns,
+		MaxLen:  defaultMaxLogValueLen,
+	},
+}
+
+// WithRedaction задаёт правила скрытия аргументов в логе запросов вместо правил по умолчанию.
+// Аргументы, обёрнутые в prettier.Redact, скрываются всегда.
+func WithRedaction(r Redaction) Option {
+	return func(p *pg) {
+		p.redaction = r
+	}
+}
+
+// forQuery объединяет общие правила с правилами запроса name
+func (r Redaction) forQuery(name string) prettier.Redaction {
+	rules := r.Redaction
+
+	q, ok := r.Queries[name]
+	if !ok {
+		return rules
+	}
+
+	rules.All = rules.All || q.All
+	rules.Args = append(append([]int(nil), rules.Args...), q.Args...)
+	rules.Columns = append(append([]string(nil), rules.Columns...), q.Columns...)
+	if q.MaxLen > 0 {
+		rules.MaxLen = q.MaxLen
+	}
+
+	return rules
+}
@@ -0,0 +1,187 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Balancer стратегия выбора реплики для чтения
+type Balancer int
+
+const (
+	// RoundRobin выбирает реплики по очереди
+	RoundRobin Balancer = iota
+	// LeastConnections выбирает реплику с наименьшим числом занятых соединений
+	LeastConnections
+)
+
+type writeKey struct{}
+
+// replica пул соединений с репликой и его состояние
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+	replay  atomic.Uint64 // последняя известная позиция применения журнала (LSN)
+	state   replicaState
+}
+
+// replicaSet набор реплик, общий для DB мастера и DB реплик одного клиента
+type replicaSet struct {
+	replicas []*replica
+	balancer Balancer
+	next     atomic.Uint64
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// WithReplicas задаёт DSN реплик, к которым pg.New создаст отдельные пулы.
+// Чтения вне транзакции через Client.Replica() направляются на реплики, всё остальное на мастер.
+func WithReplicas(dsns ...string) Option {
+	return func(p *pg) {
+		p.replicaDSNs = append(p.replicaDSNs, dsns...)
+	}
+}
+
+// WithReplicaPools задаёт уже созданные пулы реплик для NewDB
+func WithReplicaPools(pools ...*pgxpool.Pool) Option {
+	return func(p *pg) {
+		p.replicaPools = append(p.replicaPools, pools...)
+	}
+}
+
+// WithBalancer задаёт стратегию выбора реплики, по умолчанию RoundRobin
+func WithBalancer(b Balancer) Option {
+	return func(p *pg) {
+		p.balancer = b
+	}
+}
+
+// WithReadRouting включает автоматическое направление чтений вне транзакции на реплики
+// и для DB мастера. Без этой опции DB мастера выполняет все запросы на мастере, а на реплики
+// читают только через Client.Replica(). Запросы, которые пишут, например INSERT ... RETURNING
+// через QueryRowContext, при этой опции нужно выполнять с контекстом из MarkWrite.
+func WithReadRouting() Option {
+	return func(p *pg) {
+		p.readRouting = true
+	}
+}
+
+// MarkWrite помечает контекст, чтобы чтения вне транзакции выполнялись на мастере,
+// например сразу после записи, когда реплика могла ещё не догнать мастер
+func MarkWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writeKey{}, true)
+}
+
+func isWrite(ctx context.Context) bool {
+	write, _ := ctx.Value(writeKey{}).(bool)
+	return write
+}
+
+// connectReplicas создаёт пулы для DSN реплик, заданных через WithReplicas
+func (p *pg) connectReplicas(ctx context.Context) error {
+	for _, dsn := range p.replicaDSNs {
+		pool, err := pgxpool.New(ctx, dsn)
+		if err != nil {
+			for _, r := range p.replicaPools {
+				r.Close()
+			}
+
+			return fmt.Errorf("failed to connect to replica: %v", err)
+		}
+
+		p.replicaPools = append(p.replicaPools, pool)
+	}
+
+	return nil
+}
+
+// initReplicas собирает набор реплик из пулов
+func (p *pg) initReplicas() {
+	if len(p.replicaPools) == 0 {
+		return
+	}
+
+	set := &replicaSet{balancer: p.balancer}
+	for _, pool := range p.replicaPools {
+		r := &replica{pool: pool}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+
+	set.startHealthCheck(p.healthCheck)
+
+	p.replicas = set
+}
+
+// pool выбирает пул для запроса вне транзакции. Записи и чтения из помеченного контекста
+// всегда идут на мастер, остальные чтения на реплику, если это DB реплик или включена
+// WithReadRouting. Если подходящей реплики нет, используется мастер.
+func (p *pg) pool(ctx context.Context, write bool) *pgxpool.Pool {
+	if p.replicas != nil && !write && (p.replicaOnly || p.readRouting) && !isWrite(ctx) {
+		if pool := p.replicas.pick(ctx, minLSN(ctx), p.consistencyWait); pool != nil {
+			return pool
+		}
+	}
+
+	return p.dbc
+}
+
+// pick возвращает пул реплики по стратегии балансировки или nil, если здоровых реплик нет.
+// Если задан lsn, выбираются только реплики, догнавшие эту позицию.
+func (s *replicaSet) pick(ctx context.Context, lsn LSN, wait time.Duration) *pgxpool.Pool {
+	healthy := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+
+	if lsn > 0 && len(healthy) > 0 {
+		healthy = s.caughtUp(ctx, healthy, lsn, wait)
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if s.balancer == LeastConnections {
+		best := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.pool.Stat().AcquiredConns() < best.pool.Stat().AcquiredConns() {
+				best = r
+			}
+		}
+
+		return best.pool
+	}
+
+	return healthy[s.next.Add(1)%uint64(len(healthy))].pool
+}
+
+// close останавливает проверку реплик и закрывает их пулы
+func (s *replicaSet) close() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
+
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// replicaView возвращает DB, которая выполняет чтения вне транзакции и транзакции только
+// для чтения на репликах. Записи и остальные транзакции выполняются на мастере, а пулами
+// по-прежнему владеет клиент, поэтому Close у неё ничего не делает.
+func (p *pg) replicaView() *pg {
+	view := *p
+	view.replicaOnly = true
+
+	return &view
+}
@@ -0,0 +1,193 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionSettings сопоставляет настройки Postgres (например, app.user_id) ключам контекста.
+// Значения, найденные в контексте, применяются через set_config(..., true) в начале каждой
+// транзакции, начатой через BeginTx, чтобы их видели триггеры аудита и политики RLS через current_setting.
+type SessionSettings map[string]any
+
+// Option настраивает DB
+type Option func(p *pg)
+
+// WithSessionSettings включает применение настроек сессии из контекста.
+// Запросы вне транзакции при наличии значений выполняются в неявной транзакции,
+// так как set_config(..., true) действует только до её конца.
+func WithSessionSettings(s SessionSettings) Option {
+	return func(p *pg) {
+		p.session = s
+	}
+}
+
+// values возвращает отсортированные по имени настройки, значения которых есть в контексте
+func (s SessionSettings) values(ctx context.Context) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		v := ctx.Value(s[name])
+		if v == nil {
+			continue
+		}
+
+		args = append(args, name, fmt.Sprint(v))
+	}
+
+	return args
+}
+
+// Apply устанавливает значения настроек из контекста в транзакции tx
+func (s SessionSettings) Apply(ctx context.Context, tx pgx.Tx) error {
+	return applySettings(ctx, tx, s.values(ctx))
+}
+
+// settings собирает пары имя-значение для set_config: настройки сессии и search_path тенанта
+func (p *pg) settings(ctx context.Context) ([]string, error) {
+	values := p.session.values(ctx)
+
+	searchPath, err := p.tenant.searchPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if searchPath != "" {
+		values = append(values, "search_path", searchPath)
+	}
+
+	return values, nil
+}
+
+// applySettings выполняет set_config(..., true) для пар имя-значение одним запросом
+func applySettings(ctx context.Context, tx pgx.Tx, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	calls := make([]string, 0, len(values)/2)
+	args := make([]any, 0, len(values))
+	for i := 0; i < len(values); i += 2 {
+		calls = append(calls, fmt.Sprintf("set_config($%d, $%d, true)", i+1, i+2))
+		args = append(args, values[i], values[i+1])
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT "+strings.Join(calls, ", "), args...); err != nil {
+		return fmt.Errorf("failed to apply session settings: %w", err)
+	}
+
+	return nil
+}
+
+// beginPrepared начинает транзакцию и сразу применяет к ней настройки из контекста
+func (p *pg) beginPrepared(ctx context.Context, pool *pgxpool.Pool, txOptions pgx.TxOptions, values []string) (pgx.Tx, error) {
+	tx, err := pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = applySettings(ctx, tx, values); err != nil {
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// implicitTx начинает неявную транзакцию для запроса вне транзакции, если из контекста
+// нужно применить настройки сессии или search_path тенанта, в пуле pool. Если применять нечего, возвращает nil.
+func (p *pg) implicitTx(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	values, err := p.settings(ctx)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+
+	return p.beginPrepared(ctx, pool, pgx.TxOptions{}, values)
+}
+
+// finishTx коммитит неявную транзакцию при успехе и откатывает при ошибке
+func finishTx(ctx context.Context, tx pgx.Tx, err error) error {
+	if err != nil {
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// txRows завершает неявную транзакцию, когда выборка прочитана до конца или закрыта.
+// pgx закрывает выборку сам, когда Next возвращает false, поэтому вызывающий код может не вызывать Close.
+type txRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+	err  error // ошибка коммита неявной транзакции
+}
+
+func (r *txRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.finish()
+
+	return false
+}
+
+func (r *txRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+
+	return r.err
+}
+
+func (r *txRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+// finish завершает неявную транзакцию один раз, повторные вызовы ничего не делают
+func (r *txRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	if err := finishTx(r.ctx, r.tx, r.Rows.Err()); err != nil && r.Rows.Err() == nil {
+		r.err = err
+	}
+}
+
+// txRow завершает неявную транзакцию после чтения строки
+type txRow struct {
+	pgx.Row
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (r *txRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if errFinish := finishTx(r.ctx, r.tx, err); err == nil {
+		return errFinish
+	}
+
+	return err
+}
+
+// execImplicit выполняет команду в неявной транзакции
+func execImplicit(ctx context.Context, tx pgx.Tx, sql string, args ...any) (pgconn.CommandTag, error) {
+	tag, err := tx.Exec(ctx, sql, args...)
+	return tag, finishTx(ctx, tx, err)
+}
@@ -0,0 +1,92 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db/mocks"
+)
+
+// fakeRows выборка из n строк, которая, как и pgx, закрывается сама после последней строки
+type fakeRows struct {
+	pgx.Rows
+	n      int
+	err    error
+	closed int
+}
+
+func (r *fakeRows) Next() bool {
+	if r.n == 0 {
+		r.closed++
+		return false
+	}
+	r.n--
+
+	return true
+}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func (r *fakeRows) Close() {
+	r.closed++
+}
+
+func TestTxRowsFinishesWithoutClose(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	tx := mocks.NewTxMock(mc).CommitMock.Times(1).Return(nil)
+	rows := &txRows{Rows: &fakeRows{n: 2}, ctx: context.Background(), tx: tx}
+
+	read := 0
+	for rows.Next() {
+		read++
+	}
+	if read != 2 {
+		t.Fatalf("read %d rows, want 2", read)
+	}
+	if tx.CommitAfterCounter() != 1 {
+		t.Fatal("implicit transaction is still open after reading all rows")
+	}
+
+	// Close после чтения до конца не завершает транзакцию повторно
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+}
+
+func TestTxRowsRollsBackOnError(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	errRows := errors.New("connection lost")
+	tx := mocks.NewTxMock(mc).RollbackMock.Times(1).Return(nil)
+	rows := &txRows{Rows: &fakeRows{err: errRows}, ctx: context.Background(), tx: tx}
+
+	for rows.Next() {
+	}
+	rows.Close()
+
+	if !errors.Is(rows.Err(), errRows) {
+		t.Fatalf("Err = %v, want %v", rows.Err(), errRows)
+	}
+}
+
+func TestTxRowsReportsCommitError(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	errCommit := errors.New("commit failed")
+	tx := mocks.NewTxMock(mc).CommitMock.Return(errCommit)
+	rows := &txRows{Rows: &fakeRows{n: 1}, ctx: context.Background(), tx: tx}
+
+	for rows.Next() {
+	}
+
+	if !errors.Is(rows.Err(), errCommit) {
+		t.Fatalf("Err = %v, want %v", rows.Err(), errCommit)
+	}
+}
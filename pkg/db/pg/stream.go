@@ -0,0 +1,36 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+// StreamContext сканирует строки выборки по одной через pgxscan.RowScanner.
+// Учитывает транзакцию из контекста так же, как QueryContext.
+func (p *pg) StreamContext(ctx context.Context, dest interface{}, q db.Query, fn func() error, args ...interface{}) error {
+	rows, err := p.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scanner := pgxscan.NewRowScanner(rows)
+	for rows.Next() {
+		if err = scanner.Scan(dest); err != nil {
+			return db.WrapError(q.Name, err)
+		}
+
+		if err = fn(); err != nil {
+			if errors.Is(err, db.ErrStopStream) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return db.WrapError(q.Name, rows.Err())
+}
@@ -0,0 +1,65 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNoTenant запрос выполняется при включённой маршрутизации по тенантам, но тенанта нет в контексте
+var ErrNoTenant = errors.New("pg: tenant is required but not found in context")
+
+// TenantRouting маршрутизация запросов в схему тенанта для развёртываний со схемой на тенанта.
+// Схема выбирается по значению из контекста и устанавливается как search_path на время транзакции.
+type TenantRouting struct {
+	// Key ключ контекста, под которым лежит идентификатор тенанта
+	Key any
+	// Schema возвращает имя схемы для тенанта. nil означает, что имя схемы совпадает с идентификатором.
+	Schema func(tenant string) string
+}
+
+type noTenantKey struct{}
+
+// WithTenantRouting включает маршрутизацию по тенантам. Запросы без тенанта в контексте
+// завершаются ошибкой ErrNoTenant, если контекст не помечен через WithoutTenant.
+func WithTenantRouting(r TenantRouting) Option {
+	return func(p *pg) {
+		p.tenant = &r
+	}
+}
+
+// WithoutTenant помечает контекст для запросов к общим таблицам, которым тенант не нужен
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTenantKey{}, true)
+}
+
+// searchPath возвращает значение search_path для тенанта из контекста.
+// Пустая строка без ошибки означает, что search_path менять не нужно.
+func (r *TenantRouting) searchPath(ctx context.Context) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	if skip, _ := ctx.Value(noTenantKey{}).(bool); skip {
+		return "", nil
+	}
+
+	v := ctx.Value(r.Key)
+	if v == nil {
+		return "", ErrNoTenant
+	}
+
+	tenant := fmt.Sprint(v)
+	if tenant == "" {
+		return "", ErrNoTenant
+	}
+
+	schema := tenant
+	if r.Schema != nil {
+		schema = r.Schema(tenant)
+	}
+
+	return pgx.Identifier{schema}.Sanitize(), nil
+}
@@ -1,8 +1,6 @@
 package prettier
 
 import (
-	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -11,23 +9,43 @@ const (
 	PlaceholderQuestion = "?"
 )
 
+// Pretty подставляет аргументы в запрос и сворачивает его в одну строку для логирования.
+// Плейсхолдеры, пробелы и комментарии распознаются лексером, поэтому содержимое строковых
+// литералов, идентификаторов в кавычках и комментариев остаётся нетронутым.
 func Pretty(query string, placeholder string, args ...any) string {
-	for i, param := range args {
-		var value string
-		switch v := param.(type) {
-		case string:
-			value = fmt.Sprintf("%q", v)
-		case []byte:
-			value = fmt.Sprintf("%q", string(v))
+	return PrettyRedacted(query, placeholder, Redaction{}, args...)
+}
+
+// render собирает запрос из лексем в одну строку, подставляя вместо плейсхолдеров
+// результат value, которому передаётся позиция лексемы и сама лексема
+func render(tokens []token, value func(pos int, t token) string) string {
+	var b strings.Builder
+
+	for pos, t := range tokens {
+		switch t.kind {
+		case tokenSpace:
+			b.WriteByte(' ')
+		case tokenComment:
+			b.WriteString(inlineComment(t.text))
+		case tokenPlaceholder:
+			b.WriteString(value(pos, t))
 		default:
-			value = fmt.Sprintf("%v", v)
+			b.WriteString(t.text)
 		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
 
-		query = strings.Replace(query, fmt.Sprintf("%s%s", placeholder, strconv.Itoa(i+1)), value, -1)
+// inlineComment превращает однострочный комментарий в блочный, чтобы он не закомментировал
+// остаток запроса после сворачивания в одну строку
+func inlineComment(text string) string {
+	if !strings.HasPrefix(text, "--") {
+		return text
 	}
 
-	query = strings.ReplaceAll(query, "\t", "")
-	query = strings.ReplaceAll(query, "\n", " ")
+	body := strings.TrimSpace(strings.TrimPrefix(text, "--"))
+	body = strings.ReplaceAll(body, "*/", "* /")
 
-	return strings.TrimSpace(query)
-}
\ No newline at end of file
+	return "/* " + body + " */"
+}
@@ -0,0 +1,105 @@
+package prettier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Normalize приводит запрос к нормализованному виду для группировки, как это делает
+// pg_stat_statements: убирает комментарии, заменяет литералы и плейсхолдеры ($N, ?, @name) на ?,
+// сворачивает списки IN (...) и ARRAY[...] из одних значений в один элемент,
+// приводит слова к нижнему регистру и разделяет лексемы одним пробелом.
+func Normalize(query string) string {
+	var (
+		out      []string
+		adjacent bool // предыдущая лексема записана без пробела после неё
+	)
+
+	for _, t := range tokenize(query, PlaceholderNamed) {
+		switch t.kind {
+		case tokenSpace, tokenComment:
+			adjacent = false
+			continue
+		case tokenString, tokenPlaceholder:
+			out = append(out, "?")
+		case tokenWord:
+			if isDigit(t.text[0]) {
+				out = append(out, "?")
+			} else {
+				out = append(out, strings.ToLower(t.text))
+			}
+		case tokenPunct:
+			// многосимвольные операторы (<=, ::, ->>) лексер отдаёт по символу, собираем их обратно
+			if adjacent && len(out) > 0 && isOperator(out[len(out)-1]) && isOperator(t.text) {
+				out[len(out)-1] += t.text
+			} else if t.text == "?" || isPositional(t.text) {
+				out = append(out, "?")
+			} else {
+				out = append(out, t.text)
+			}
+		default:
+			out = append(out, t.text)
+		}
+
+		adjacent = true
+	}
+
+	return strings.Join(collapseLists(out), " ")
+}
+
+// Fingerprint возвращает стабильный хеш нормализованного запроса. Запросы, отличающиеся
+// только значениями, пробелами, комментариями и длиной списков IN, получают одинаковый отпечаток.
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(Normalize(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// isPositional сообщает, что лексема является плейсхолдером $N: при разборе в стиле
+// PlaceholderNamed лексер отдаёт их как обычные символы
+func isPositional(s string) bool {
+	return len(s) > 1 && s[0] == '$' && strings.Trim(s[1:], "0123456789") == ""
+}
+
+// isOperator сообщает, что строка состоит только из символов операторов
+func isOperator(s string) bool {
+	return s != "" && strings.Trim(s, "+-*/<>=~!@#%^&|`:") == ""
+}
+
+// collapseLists сворачивает in ( ? , ? , ? ) и array [ ? , ? ] в in ( ? ) и array [ ? ]
+func collapseLists(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+
+		if (tokens[i] != "in" || !at(tokens, i+1, "(")) && (tokens[i] != "array" || !at(tokens, i+1, "[")) {
+			continue
+		}
+
+		open, close := tokens[i+1], ")"
+		if open == "[" {
+			close = "]"
+		}
+
+		// список должен состоять только из ? через запятую
+		j := i + 2
+		for at(tokens, j, "?") && (at(tokens, j+1, ",") || at(tokens, j+1, close)) {
+			j += 2
+			if tokens[j-1] == close {
+				break
+			}
+		}
+
+		if j > i+2 && at(tokens, j-1, close) {
+			out = append(out, open, "?", close)
+			i = j - 1
+		}
+	}
+
+	return out
+}
+
+func at(tokens []string, i int, s string) bool {
+	return i < len(tokens) && tokens[i] == s
+}
@@ -0,0 +1,66 @@
+package prettier
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "literals placeholders and comments",
+			query: "SELECT * FROM t WHERE id = $1 AND name = 'x' -- comment",
+			want:  "select * from t where id = ? and name = ?",
+		},
+		{
+			name:  "in list collapsed",
+			query: "select id from t where id in (1, 2, 3)",
+			want:  "select id from t where id in ( ? )",
+		},
+		{
+			name:  "array collapsed",
+			query: "SELECT ARRAY[$1, $2]",
+			want:  "select array [ ? ]",
+		},
+		{
+			name:  "multi-character operators",
+			query: "SELECT a::text FROM t WHERE b <= @b AND c ->> 'k' = ?",
+			want:  "select a :: text from t where b <= ? and c ->> ? = ?",
+		},
+		{
+			name:  "quoted identifier kept",
+			query: `SELECT "Name" FROM t`,
+			want:  `select "Name" from t`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.query); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("SELECT * FROM t WHERE id IN ($1, $2) AND name = 'x'")
+
+	same := []string{
+		"select *  from T where id in (1,2,3) and name='y'",
+		"SELECT * /* c */ FROM t\nWHERE id IN (?) AND name = @name",
+	}
+	for _, q := range same {
+		if got := Fingerprint(q); got != base {
+			t.Errorf("Fingerprint(%q) = %s, want %s", q, got, base)
+		}
+	}
+
+	other := "SELECT * FROM t WHERE id IN ($1, $2) OR name = 'x'"
+	if Fingerprint(other) == base {
+		t.Errorf("Fingerprint(%q) must differ from %s", other, base)
+	}
+}
@@ -0,0 +1,224 @@
+package prettier
+
+import (
+	"strings"
+)
+
+// clauseKeywords ключевые слова, с которых начинается новая строка
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "JOIN": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"INNER": true, "CROSS": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "INSERT": true,
+	"VALUES": true, "UPDATE": true, "SET": true, "DELETE": true, "RETURNING": true, "WITH": true,
+	"WINDOW": true, "FOR": true,
+}
+
+// joinPrefixes слова, после которых JOIN и OUTER продолжают ту же строку
+var joinPrefixes = map[string]bool{
+	"LEFT": true, "RIGHT": true, "FULL": true, "INNER": true, "CROSS": true, "OUTER": true, "NATURAL": true,
+}
+
+// keywords ключевые слова, которые переводятся в верхний регистр при FormatOptions.Uppercase
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true,
+	"CASE": true, "CONFLICT": true, "CROSS": true, "DEFAULT": true, "DELETE": true, "DESC": true,
+	"DISTINCT": true, "DO": true, "ELSE": true, "END": true, "EXCEPT": true, "EXISTS": true,
+	"FALSE": true, "FOR": true, "FROM": true, "FULL": true, "GROUP": true, "HAVING": true,
+	"ILIKE": true, "IN": true, "INNER": true, "INSERT": true, "INTERSECT": true, "INTO": true,
+	"IS": true, "JOIN": true, "LEFT": true, "LIKE": true, "LIMIT": true, "LOCKED": true,
+	"NATURAL": true, "NOT": true, "NOTHING": true, "NULL": true, "NULLS": true, "OFFSET": true,
+	"ON": true, "OR": true, "ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true,
+	"RETURNING": true, "RIGHT": true, "SELECT": true, "SET": true, "SHARE": true, "SKIP": true,
+	"THEN": true, "TRUE": true, "UNION": true, "UPDATE": true, "USING": true, "VALUES": true,
+	"WHEN": true, "WHERE": true, "WINDOW": true, "WITH": true,
+}
+
+// FormatOptions параметры многострочного форматирования
+type FormatOptions struct {
+	// Indent строка одного уровня отступа, по умолчанию два пробела
+	Indent string
+	// Uppercase переводит ключевые слова в верхний регистр
+	Uppercase bool
+	// Redaction правила скрытия и усечения аргументов, как в PrettyRedacted
+	Redaction Redaction
+}
+
+// Format подставляет аргументы так же, как Pretty, но раскладывает запрос по строкам:
+// каждое предложение (SELECT, FROM, JOIN, WHERE, GROUP BY и т.д.) начинается с новой строки,
+// условия AND/OR получают дополнительный отступ, а подзапросы в скобках вкладываются с отступом.
+// Меняются только пробелы и регистр ключевых слов, поэтому результат остаётся валидным SQL.
+func Format(query string, placeholder string, opts FormatOptions, args ...any) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+
+	f := &formatter{
+		tokens: tokenize(query, placeholder),
+		opts:   opts,
+	}
+
+	if len(opts.Redaction.Columns) > 0 {
+		f.columns = placeholderColumns(f.tokens)
+	}
+	f.args = newArguments(placeholder, args)
+
+	return f.format()
+}
+
+// formatter состояние многострочного форматирования
+type formatter struct {
+	tokens  []token
+	opts    FormatOptions
+	args    arguments
+	columns map[int]string
+
+	b       strings.Builder
+	depth   int    // уровень отступа текущего предложения
+	parens  []bool // стек скобок: true для подзапросов
+	prev    string // предыдущая значимая лексема в верхнем регистре
+	between bool   // внутри BETWEEN ... AND, где AND не переносится
+	space   bool   // перед следующей лексемой нужен пробел
+}
+
+func (f *formatter) format() string {
+	for pos, t := range f.tokens {
+		switch t.kind {
+		case tokenSpace:
+			f.space = true
+			continue
+		case tokenComment:
+			f.write(inlineComment(t.text))
+			continue
+		case tokenPlaceholder:
+			f.write(f.value(pos, t))
+		case tokenWord:
+			f.word(pos, t.text)
+		case tokenPunct:
+			f.punct(pos, t.text)
+		default:
+			f.write(t.text)
+		}
+
+		f.prev = strings.ToUpper(t.text)
+	}
+
+	return strings.TrimSpace(f.b.String())
+}
+
+// value подставляет аргумент вместо плейсхолдера по правилам Redaction
+func (f *formatter) value(pos int, t token) string {
+	arg, ok := f.args.lookup(t)
+	if !ok {
+		return t.text
+	}
+
+	if f.opts.Redaction.hides(t.index, f.columns[pos]) {
+		return redacted
+	}
+
+	return f.opts.Redaction.literal(arg)
+}
+
+func (f *formatter) word(pos int, text string) {
+	upper := strings.ToUpper(text)
+	if f.opts.Uppercase && keywords[upper] {
+		text = upper
+	}
+
+	atClause := len(f.parens) == 0 || f.parens[len(f.parens)-1]
+
+	switch {
+	case upper == "BETWEEN":
+		f.between = true
+	case (upper == "AND" || upper == "OR") && f.between:
+		f.between = false
+	case (upper == "AND" || upper == "OR") && atClause:
+		f.newline(f.depth + 1)
+	case clauseKeywords[upper] && atClause && f.prev != "(" && !f.continuesClause(upper) && !f.isCall(pos):
+		f.newline(f.depth)
+	}
+
+	f.write(text)
+}
+
+// continuesClause сообщает, что слово продолжает предыдущее предложение, например JOIN после LEFT
+// или UPDATE в FOR UPDATE
+func (f *formatter) continuesClause(upper string) bool {
+	switch upper {
+	case "JOIN", "OUTER":
+		return joinPrefixes[f.prev]
+	case "UPDATE":
+		return f.prev == "FOR" || f.prev == "DO"
+	case "FROM":
+		return f.prev == "DELETE" || f.prev == "DISTINCT"
+	}
+
+	return false
+}
+
+// isCall сообщает, что слово является вызовом одноимённой функции left(name, 3) или right(name, 3)
+func (f *formatter) isCall(pos int) bool {
+	upper := strings.ToUpper(f.tokens[pos].text)
+	if upper != "LEFT" && upper != "RIGHT" {
+		return false
+	}
+
+	next := f.nextSignificant(pos)
+	return next >= 0 && f.tokens[next].text == "("
+}
+
+func (f *formatter) punct(pos int, text string) {
+	switch text {
+	case "(":
+		next := f.nextSignificant(pos)
+		subquery := next >= 0 && f.tokens[next].kind == tokenWord &&
+			(strings.EqualFold(f.tokens[next].text, "SELECT") || strings.EqualFold(f.tokens[next].text, "WITH"))
+
+		f.write(text)
+		f.parens = append(f.parens, subquery)
+		if subquery {
+			f.depth++
+			f.newline(f.depth)
+		}
+	case ")":
+		subquery := len(f.parens) > 0 && f.parens[len(f.parens)-1]
+		if len(f.parens) > 0 {
+			f.parens = f.parens[:len(f.parens)-1]
+		}
+
+		if subquery {
+			f.depth--
+			f.newline(f.depth)
+		}
+		f.write(text)
+	default:
+		f.write(text)
+	}
+}
+
+// newline переносит строку с отступом level. Пробел перед следующей лексемой больше не нужен.
+func (f *formatter) newline(level int) {
+	if f.b.Len() > 0 {
+		f.b.WriteString("\n" + strings.Repeat(f.opts.Indent, level))
+	}
+	f.space = false
+}
+
+func (f *formatter) write(text string) {
+	if f.space && f.b.Len() > 0 {
+		f.b.WriteByte(' ')
+	}
+	f.space = false
+
+	f.b.WriteString(text)
+}
+
+func (f *formatter) nextSignificant(pos int) int {
+	for pos++; pos < len(f.tokens); pos++ {
+		if f.tokens[pos].kind != tokenSpace && f.tokens[pos].kind != tokenComment {
+			return pos
+		}
+	}
+
+	return -1
+}
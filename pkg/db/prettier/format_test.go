@@ -0,0 +1,83 @@
+package prettier
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		opts  FormatOptions
+		args  []any
+		want  string
+	}{
+		{
+			name:  "clauses and conditions",
+			query: "select a, b from t left join u on u.id = t.id where a = $1 and b between 1 and 2 order by a",
+			opts:  FormatOptions{Uppercase: true},
+			args:  []any{5},
+			want: "SELECT a, b\n" +
+				"FROM t\n" +
+				"LEFT JOIN u ON u.id = t.id\n" +
+				"WHERE a = 5\n" +
+				"  AND b BETWEEN 1 AND 2\n" +
+				"ORDER BY a",
+		},
+		{
+			name:  "subquery",
+			query: "select * from (select id from t where a = 'it''s') s where s.id > $1",
+			opts:  FormatOptions{Uppercase: true},
+			args:  []any{7},
+			want: "SELECT *\n" +
+				"FROM (\n" +
+				"  SELECT id\n" +
+				"  FROM t\n" +
+				"  WHERE a = 'it''s'\n" +
+				") s\n" +
+				"WHERE s.id > 7",
+		},
+		{
+			name:  "function call and outer join",
+			query: "select left(name, 3) from t left outer join u on true",
+			opts:  FormatOptions{Uppercase: true},
+			want: "SELECT LEFT(name, 3)\n" +
+				"FROM t\n" +
+				"LEFT OUTER JOIN u ON TRUE",
+		},
+		{
+			name:  "locking clause",
+			query: "select id from outbox for update skip locked",
+			opts:  FormatOptions{Uppercase: true},
+			want: "SELECT id\n" +
+				"FROM outbox\n" +
+				"FOR UPDATE SKIP LOCKED",
+		},
+		{
+			name:  "insert values",
+			query: "INSERT INTO t (a, b) VALUES ($1, $2) RETURNING id",
+			args:  []any{"a", nil},
+			want: "INSERT INTO t (a, b)\n" +
+				"VALUES ('a', NULL)\n" +
+				"RETURNING id",
+		},
+		{
+			name:  "redaction and custom indent",
+			query: "update users set password = $1 where id = $2 and active",
+			opts:  FormatOptions{Indent: "\t", Redaction: Redaction{Columns: SensitiveColumns}},
+			args:  []any{"secret", 7},
+			want: "update users\n" +
+				"set password = '[REDACTED]'\n" +
+				"where id = 7\n" +
+				"\tand active",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.query, PlaceholderDollar, tt.opts, tt.args...); got != tt.want {
+				t.Errorf("Format(%q) =\n%s\nwant\n%s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
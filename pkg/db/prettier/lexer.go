@@ -0,0 +1,224 @@
+package prettier
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind тип лексемы SQL
+type tokenKind int
+
+const (
+	tokenWord        tokenKind = iota // ключевое слово, идентификатор или число
+	tokenSpace                        // пробельные символы
+	tokenString                       // строковый литерал: '...', E'...', $tag$...$tag$
+	tokenIdent                        // идентификатор в двойных кавычках
+	tokenComment                      // комментарий -- ... или /* ... */
+	tokenPlaceholder                  // плейсхолдер $N или ?
+	tokenPunct                        // прочие символы: операторы, скобки, запятые
+)
+
+// token лексема SQL. Для позиционных плейсхолдеров index содержит номер аргумента, начиная с нуля,
+// для именованных name содержит имя аргумента, а index равен -1.
+type token struct {
+	kind  tokenKind
+	text  string
+	index int
+	name  string
+}
+
+// tokenize разбивает запрос на лексемы. Плейсхолдеры распознаются только в указанном стиле:
+// $N для PlaceholderDollar, позиционные ? для PlaceholderQuestion и @name для PlaceholderNamed. Содержимое литералов,
+// идентификаторов в кавычках и комментариев не разбирается.
+func tokenize(query string, placeholder string) []token {
+	var (
+		tokens   []token
+		question int
+	)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+
+		switch {
+		case isSpace(c):
+			for i < len(query) && isSpace(query[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenSpace, text: query[start:i]})
+
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			i = indexFrom(query, i, "\n")
+			tokens = append(tokens, token{kind: tokenComment, text: query[start:i]})
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+			tokens = append(tokens, token{kind: tokenComment, text: query[start:i]})
+
+		case c == '\'':
+			i = skipQuoted(query, i, '\'', false)
+			tokens = append(tokens, token{kind: tokenString, text: query[start:i]})
+
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'' && !wordBefore(query, i):
+			i = skipQuoted(query, i+1, '\'', true)
+			tokens = append(tokens, token{kind: tokenString, text: query[start:i]})
+
+		case c == '"':
+			i = skipQuoted(query, i, '"', false)
+			tokens = append(tokens, token{kind: tokenIdent, text: query[start:i]})
+
+		case c == '$':
+			if end, ok := dollarQuote(query, i); ok {
+				i = end
+				tokens = append(tokens, token{kind: tokenString, text: query[start:i]})
+				break
+			}
+
+			i++
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+
+			n, err := strconv.Atoi(query[start+1 : i])
+			if placeholder == PlaceholderDollar && err == nil && n > 0 {
+				tokens = append(tokens, token{kind: tokenPlaceholder, text: query[start:i], index: n - 1})
+			} else {
+				tokens = append(tokens, token{kind: tokenPunct, text: query[start:i]})
+			}
+
+		case c == '?' && placeholder == PlaceholderQuestion:
+			i++
+			tokens = append(tokens, token{kind: tokenPlaceholder, text: "?", index: question})
+			question++
+
+		case c == '@' && placeholder == PlaceholderNamed && i+1 < len(query) && isNameStart(query[i+1]):
+			i++
+			for i < len(query) && (isNameStart(query[i]) || isDigit(query[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenPlaceholder, text: query[start:i], index: -1, name: query[start+1 : i]})
+
+		case isWordStart(query, i):
+			for i < len(query) && isWordPart(query, i) {
+				_, size := utf8.DecodeRuneInString(query[i:])
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: query[start:i]})
+
+		default:
+			_, size := utf8.DecodeRuneInString(query[i:])
+			i += size
+			tokens = append(tokens, token{kind: tokenPunct, text: query[start:i]})
+		}
+	}
+
+	return tokens
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isNameStart сообщает, что символ может начинать имя аргумента pgx.NamedArgs
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordPart(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordBefore сообщает, что символ в позиции i продолжает слово, например "name'" не является E-строкой
+func wordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// indexFrom возвращает позицию подстроки sub начиная с from или конец строки, если её нет
+func indexFrom(s string, from int, sub string) int {
+	if j := strings.Index(s[from:], sub); j >= 0 {
+		return from + j
+	}
+
+	return len(s)
+}
+
+// skipBlockComment пропускает комментарий /* ... */ с учётом вложенности
+func skipBlockComment(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+
+	return i
+}
+
+// skipQuoted пропускает литерал в кавычках quote, начиная с открывающей кавычки.
+// Удвоенная кавычка внутри литерала экранирует её, а в E-строках экранирует и обратный слеш.
+func skipQuoted(s string, i int, quote byte, backslash bool) int {
+	for i++; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// dollarQuote распознаёт строку в долларовых кавычках $tag$...$tag$ и возвращает позицию после неё
+func dollarQuote(s string, i int) (int, bool) {
+	j := i + 1
+	for j < len(s) && s[j] != '$' {
+		if !isWordPart(s, j) || s[j] == '$' || (j == i+1 && isDigit(s[j])) {
+			return 0, false
+		}
+		j++
+	}
+	if j >= len(s) {
+		return 0, false
+	}
+
+	tag := s[i : j+1]
+	end := strings.Index(s[j+1:], tag)
+	if end < 0 {
+		return len(s), true
+	}
+
+	return j + 1 + end + len(tag), true
+}
@@ -0,0 +1,137 @@
+package prettier
+
+import (
+	"testing"
+)
+
+func TestPrettyPlaceholders(t *testing.T) {
+	args := []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		name        string
+		query       string
+		placeholder string
+		args        []any
+		want        string
+	}{
+		{
+			name:        "dollar placeholders differ from their prefixes",
+			query:       "SELECT $1, $10",
+			placeholder: PlaceholderDollar,
+			args:        args,
+			want:        "SELECT 1, 10",
+		},
+		{
+			name:        "placeholder inside string literal",
+			query:       "SELECT '$1' || $1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT '$1' || 'x'",
+		},
+		{
+			name:        "escaped quote inside E-string",
+			query:       `SELECT E'\'$1', $1`,
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        `SELECT E'\'$1', 'x'`,
+		},
+		{
+			name:        "dollar-quoted string with tag",
+			query:       "SELECT $tag$ $1 ' $tag$, $1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT $tag$ $1 ' $tag$, 'x'",
+		},
+		{
+			name:        "dollar-quoted string without tag",
+			query:       "SELECT $$ $1 $$, $1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT $$ $1 $$, 'x'",
+		},
+		{
+			name:        "nested block comment",
+			query:       "SELECT /* a /* $1 */ b */ $1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT /* a /* $1 */ b */ 'x'",
+		},
+		{
+			name:        "line comment becomes block comment",
+			query:       "SELECT 1 -- $1 comment\n, $1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT 1 /* $1 comment */ , 'x'",
+		},
+		{
+			name:        "whitespace inside literal is preserved",
+			query:       "SELECT 'a\tb  c',\n\t$1",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT 'a\tb  c', 'x'",
+		},
+		{
+			name:        "quoted identifier",
+			query:       `SELECT $1::text AS "$1"`,
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        `SELECT 'x'::text AS "$1"`,
+		},
+		{
+			name:        "positional question marks",
+			query:       "SELECT ?, '?', ?",
+			placeholder: PlaceholderQuestion,
+			args:        []any{1, 2},
+			want:        "SELECT 1, '?', 2",
+		},
+		{
+			name:        "missing argument keeps placeholder",
+			query:       "SELECT $2",
+			placeholder: PlaceholderDollar,
+			args:        []any{"x"},
+			want:        "SELECT $2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Pretty(tt.query, tt.placeholder, tt.args...); got != tt.want {
+				t.Errorf("Pretty(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizePlaceholderIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		placeholder string
+		want        []int
+	}{
+		{name: "dollar", query: "$1 $10 $2", placeholder: PlaceholderDollar, want: []int{0, 9, 1}},
+		{name: "question", query: "? '?' ? ?", placeholder: PlaceholderQuestion, want: []int{0, 1, 2}},
+		{name: "dollar ignored in question style", query: "$1 ?", placeholder: PlaceholderQuestion, want: []int{0}},
+		{name: "zero is not a placeholder", query: "$0", placeholder: PlaceholderDollar, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, tok := range tokenize(tt.query, tt.placeholder) {
+				if tok.kind == tokenPlaceholder {
+					got = append(got, tok.index)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("placeholders = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("placeholders = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package prettier
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayout формат timestamptz, который Postgres разбирает без потери точности
+const timestampLayout = "2006-01-02 15:04:05.999999-07:00"
+
+// literal отображает значение аргумента в виде литерала Postgres, который можно
+// скопировать из лога и выполнить: NULL, строки в одинарных кавычках, ARRAY[...] и т.д.
+// Строковые и бинарные значения длиннее maxLen байт усекаются, в том числе элементы массивов,
+// JSON и результаты driver.Valuer. Ноль отключает усечение.
+func literal(param any, maxLen int) string {
+	if param == nil {
+		return "NULL"
+	}
+
+	// разыменовываем указатели, nil-указатель соответствует NULL
+	rv := reflect.ValueOf(param)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "NULL"
+		}
+		if _, ok := rv.Interface().(driver.Valuer); ok {
+			break
+		}
+		rv = rv.Elem()
+		param = rv.Interface()
+	}
+
+	// pgx передаёт nil-срезы и nil-карты как NULL, а не как пустой массив или объект
+	if _, ok := param.(driver.Valuer); !ok && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return "NULL"
+	}
+
+	switch v := param.(type) {
+	case driver.Valuer:
+		value, err := v.Value()
+		if err != nil {
+			return quote(fmt.Sprintf("<%v>", err))
+		}
+		if _, ok := value.(driver.Valuer); ok {
+			return text(fmt.Sprint(value), "", maxLen)
+		}
+
+		return literal(value, maxLen)
+	case string:
+		return text(v, "", maxLen)
+	case json.RawMessage:
+		return text(string(v), "::jsonb", maxLen)
+	case []byte:
+		if maxLen > 0 && len(v) > maxLen {
+			return bytea(v[:maxLen]) + truncated(len(v))
+		}
+
+		return bytea(v)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+
+		return "FALSE"
+	case float32:
+		return float(float64(v), 32)
+	case float64:
+		return float(v, 64)
+	case time.Time:
+		return quote(v.Format(timestampLayout)) + "::timestamptz"
+	case time.Duration:
+		return quote(strconv.FormatInt(v.Microseconds(), 10)+" microseconds") + "::interval"
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return float(rv.Float(), 64)
+	case reflect.String:
+		return text(rv.String(), "", maxLen)
+	case reflect.Bool:
+		return literal(rv.Bool(), 0)
+	}
+
+	// UUID разных библиотек, например google/uuid, объявлены как массив из 16 байт
+	if rv.Kind() == reflect.Array && rv.Len() == 16 && rv.Type().Elem().Kind() == reflect.Uint8 {
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(rv.Index(i).Uint())
+		}
+
+		return quote(uuid(b)) + "::uuid"
+	}
+
+	// String проверяется после базовых типов: перечисления на основе int и time.Month
+	// pgx передаёт числами, а не строками
+	if v, ok := param.(fmt.Stringer); ok {
+		return text(v.String(), "", maxLen)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return literal(rv.Bytes(), maxLen)
+		}
+		if rv.Len() == 0 {
+			return "'{}'"
+		}
+
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = literal(rv.Index(i).Interface(), maxLen)
+		}
+
+		return "ARRAY[" + strings.Join(items, ", ") + "]"
+	case reflect.Map, reflect.Struct:
+		// pgx кодирует карты и структуры без собственного кодека как JSON
+		data, err := json.Marshal(param)
+		if err != nil {
+			return text(fmt.Sprintf("%v", param), "", maxLen)
+		}
+
+		return text(string(data), "::jsonb", maxLen)
+	default:
+		return text(fmt.Sprintf("%v", param), "", maxLen)
+	}
+}
+
+// text заключает строку в кавычки и добавляет приведение типа cast. Строка длиннее maxLen
+// байт усекается и выводится без приведения, так как обрезанный JSON уже не разобрать.
+func text(s, cast string, maxLen int) string {
+	if maxLen > 0 && len(s) > maxLen {
+		return quote(cut(s, maxLen)) + truncated(len(s))
+	}
+
+	return quote(s) + cast
+}
+
+// bytea отображает байты в шестнадцатеричном формате bytea
+func bytea(b []byte) string {
+	return "'\\x" + hex.EncodeToString(b) + "'::bytea"
+}
+
+// quote заключает строку в одинарные кавычки, удваивая кавычки внутри
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// float отображает число с плавающей точкой, специальные значения передаются строкой
+func float(v float64, bits int) string {
+	switch {
+	case math.IsNaN(v):
+		return "'NaN'::float8"
+	case math.IsInf(v, 1):
+		return "'Infinity'::float8"
+	case math.IsInf(v, -1):
+		return "'-Infinity'::float8"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, bits)
+	}
+}
+
+// uuid форматирует 16 байт в каноническом виде UUID
+func uuid(b [16]byte) string {
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,90 @@
+package prettier
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// status перечисление на основе int со строковым представлением
+type status int
+
+func (s status) String() string {
+	return "active"
+}
+
+// uuidBytes UUID, объявленный как массив байт, как в google/uuid
+type uuidBytes [16]byte
+
+func (u uuidBytes) String() string {
+	return "not used"
+}
+
+// money значение со строковым представлением, которое pgx передаёт через String
+type money struct {
+	units int64
+}
+
+func (m money) String() string {
+	return "12.50"
+}
+
+func TestLiteral(t *testing.T) {
+	str := "it's"
+	var nilStr *string
+
+	tests := []struct {
+		name  string
+		param any
+		want  string
+	}{
+		{name: "nil", param: nil, want: "NULL"},
+		{name: "nil pointer", param: nilStr, want: "NULL"},
+		{name: "pointer", param: &str, want: "'it''s'"},
+		{name: "string", param: "it's", want: "'it''s'"},
+		{name: "int", param: 42, want: "42"},
+		{name: "uint", param: uint8(7), want: "7"},
+		{name: "bool", param: true, want: "TRUE"},
+		{name: "float", param: 1.5, want: "1.5"},
+		{name: "nan", param: math.NaN(), want: "'NaN'::float8"},
+		{name: "bytes", param: []byte{0xde, 0xad}, want: `'\xdead'::bytea`},
+		{name: "nil slice", param: []int(nil), want: "NULL"},
+		{name: "empty slice", param: []int{}, want: "'{}'"},
+		{name: "array", param: []string{"a", "b"}, want: "ARRAY['a', 'b']"},
+		{name: "json", param: json.RawMessage(`{"a":1}`), want: `'{"a":1}'::jsonb`},
+		{name: "map", param: map[string]int{"a": 1}, want: `'{"a":1}'::jsonb`},
+		{name: "duration", param: 1500 * time.Millisecond, want: "'1500000 microseconds'::interval"},
+		{
+			name:  "time",
+			param: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+			want:  "'2024-01-02 03:04:05.000006+00:00'::timestamptz",
+		},
+		{name: "int enum with String", param: status(3), want: "3"},
+		{name: "month", param: time.March, want: "3"},
+		{
+			name:  "uuid",
+			param: [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0},
+			want:  "'12345678-9abc-def0-1234-56789abcdef0'::uuid",
+		},
+		{
+			name:  "named uuid",
+			param: uuidBytes{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0},
+			want:  "'12345678-9abc-def0-1234-56789abcdef0'::uuid",
+		},
+		{name: "struct with String", param: money{units: 1250}, want: "'12.50'"},
+		{name: "ip", param: net.ParseIP("10.0.0.1"), want: "'10.0.0.1'"},
+		{name: "valuer", param: sql.NullString{String: "a", Valid: true}, want: "'a'"},
+		{name: "null valuer", param: sql.NullInt64{}, want: "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := literal(tt.param, 0); got != tt.want {
+				t.Fatalf("literal(%#v) = %s, want %s", tt.param, got, tt.want)
+			}
+		})
+	}
+}
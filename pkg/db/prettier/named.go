@@ -0,0 +1,49 @@
+package prettier
+
+import (
+	"reflect"
+)
+
+// PlaceholderNamed стиль именованных плейсхолдеров pgx: @name
+const PlaceholderNamed = "@"
+
+// arguments значения аргументов запроса: позиционные или именованные
+type arguments struct {
+	positional []any
+	named      map[string]any
+}
+
+// newArguments разбирает аргументы. Для PlaceholderNamed единственный аргумент должен быть
+// картой со строковыми ключами, например pgx.NamedArgs.
+func newArguments(placeholder string, args []any) arguments {
+	if placeholder != PlaceholderNamed {
+		return arguments{positional: args}
+	}
+
+	named := make(map[string]any)
+	if len(args) == 1 {
+		rv := reflect.ValueOf(args[0])
+		if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			iter := rv.MapRange()
+			for iter.Next() {
+				named[iter.Key().String()] = iter.Value().Interface()
+			}
+		}
+	}
+
+	return arguments{named: named}
+}
+
+// lookup возвращает значение для плейсхолдера
+func (a arguments) lookup(t token) (any, bool) {
+	if t.name != "" {
+		v, ok := a.named[t.name]
+		return v, ok
+	}
+
+	if t.index < 0 || t.index >= len(a.positional) {
+		return nil, false
+	}
+
+	return a.positional[t.index], true
+}
@@ -0,0 +1,325 @@
+package prettier
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// redacted литерал, который подставляется вместо скрытого значения
+const redacted = "'[REDACTED]'"
+
+// SensitiveColumns имена колонок, значения которых обычно нельзя писать в лог.
+// Колонка считается чувствительной, если её имя содержит одну из этих подстрок.
+var SensitiveColumns = []string{"password", "passwd", "secret", "token", "hash", "api_key", "credential"}
+
+// Sensitive обёртка аргумента, значение которого никогда не попадает в лог.
+// Передаётся в запрос как обычный аргумент: pgx получает исходное значение через driver.Valuer,
+// а пакет pg разворачивает обёртку до передачи аргументов драйверу.
+type Sensitive struct {
+	value any
+}
+
+// Redact помечает аргумент как чувствительный
+func Redact(v any) Sensitive {
+	return Sensitive{value: v}
+}
+
+// Value возвращает исходное значение для драйвера
+func (s Sensitive) Value() (driver.Value, error) {
+	if v, ok := s.value.(driver.Valuer); ok {
+		return v.Value()
+	}
+
+	return s.value, nil
+}
+
+func (s Sensitive) String() string {
+	return "[REDACTED]"
+}
+
+// Unwrap заменяет обёртки Sensitive на исходные значения
+func Unwrap(args []any) []any {
+	var out []any
+	for i, arg := range args {
+		s, ok := arg.(Sensitive)
+		if !ok {
+			continue
+		}
+
+		if out == nil {
+			out = append([]any(nil), args...)
+		}
+		out[i] = s.value
+	}
+
+	if out == nil {
+		return args
+	}
+
+	return out
+}
+
+// Redaction правила скрытия и усечения аргументов при логировании
+type Redaction struct {
+	// All скрывает все аргументы запроса
+	All bool
+	// Args номера аргументов, начиная с 1, которые нужно скрыть
+	Args []int
+	// Columns подстроки имён колонок, значения которых нужно скрыть, например SensitiveColumns.
+	// Колонка определяется по сравнению col = $N, в том числе lower(col) = lower($N) и col = ANY($N),
+	// списку col IN ($N, ...), присваиванию SET col = $N и списку колонок INSERT ... VALUES.
+	// В остальных случаях чувствительное значение нужно обернуть в Redact.
+	Columns []string
+	// MaxLen максимальная длина строковых и бинарных значений, длиннее которой они усекаются,
+	// в том числе внутри массивов, JSON и результатов driver.Valuer. Ноль отключает усечение.
+	MaxLen int
+}
+
+// PrettyRedacted работает как Pretty, но скрывает и усекает аргументы по правилам r
+func PrettyRedacted(query string, placeholder string, r Redaction, args ...any) string {
+	tokens := tokenize(query, placeholder)
+
+	var columns map[int]string
+	if len(r.Columns) > 0 {
+		columns = placeholderColumns(tokens)
+	}
+
+	values := newArguments(placeholder, args)
+
+	return render(tokens, func(pos int, t token) string {
+		arg, ok := values.lookup(t)
+		if !ok {
+			return t.text
+		}
+
+		if r.hides(t.index, columns[pos]) {
+			return redacted
+		}
+
+		return r.literal(arg)
+	})
+}
+
+// hides сообщает, что аргумент с номером index (с нуля) для колонки column нужно скрыть
+func (r Redaction) hides(index int, column string) bool {
+	if r.All {
+		return true
+	}
+
+	for _, n := range r.Args {
+		if n == index+1 {
+			return true
+		}
+	}
+
+	column = strings.ToLower(column)
+	for _, c := range r.Columns {
+		if column != "" && strings.Contains(column, strings.ToLower(c)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literal отображает аргумент, скрывая Sensitive и усекая длинные значения
+func (r Redaction) literal(arg any) string {
+	if _, ok := arg.(Sensitive); ok {
+		return redacted
+	}
+
+	return literal(arg, r.MaxLen)
+}
+
+// cut обрезает строку до n байт по границе символа, чтобы не разрезать многобайтовую руну
+func cut(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// truncated комментарий, отмечающий усечённое значение
+func truncated(size int) string {
+	return fmt.Sprintf(" /* truncated, %d bytes */", size)
+}
+
+// placeholderColumns сопоставляет позициям плейсхолдеров в tokens имена колонок,
+// к которым относятся их значения
+func placeholderColumns(tokens []token) map[int]string {
+	columns := make(map[int]string)
+
+	for pos, t := range tokens {
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+
+		j := valueStart(tokens, pos)
+		if j >= 0 && tokens[j].kind == tokenWord && strings.EqualFold(tokens[j].text, "IN") {
+			// col IN ($1, $2), col NOT IN ($1, $2)
+			j = prevSignificant(tokens, j)
+			if j >= 0 && tokens[j].kind == tokenWord && strings.EqualFold(tokens[j].text, "NOT") {
+				j = prevSignificant(tokens, j)
+			}
+		} else {
+			// col = $N, col <> $N, SET col = $N
+			if j < 0 || tokens[j].kind != tokenPunct || !isComparison(tokens[j].text) {
+				continue
+			}
+			for j >= 0 && tokens[j].kind == tokenPunct && isComparison(tokens[j].text) {
+				j = prevSignificant(tokens, j)
+			}
+		}
+
+		if column := operand(tokens, j); column != "" {
+			columns[pos] = column
+		}
+	}
+
+	insertColumns(tokens, columns)
+
+	return columns
+}
+
+// valueStart возвращает позицию лексемы перед значением плейсхолдера pos, пропуская
+// скобки, вызовы функций вроде lower($1) и ANY($1) и предыдущие элементы списков IN ($1, $2)
+func valueStart(tokens []token, pos int) int {
+	j := prevSignificant(tokens, pos)
+	for j >= 0 {
+		switch tokens[j].text {
+		case ",":
+			j = openParen(tokens, j)
+		case "(":
+			j = prevSignificant(tokens, j)
+			if j < 0 || tokens[j].kind != tokenWord || strings.EqualFold(tokens[j].text, "IN") {
+				return j
+			}
+			// имя функции
+			j = prevSignificant(tokens, j)
+		default:
+			return j
+		}
+	}
+
+	return j
+}
+
+// openParen возвращает позицию открывающей скобки, внутри которой находится лексема pos, или -1
+func openParen(tokens []token, pos int) int {
+	depth := 0
+	for pos--; pos >= 0; pos-- {
+		switch tokens[pos].text {
+		case ")":
+			depth++
+		case "(":
+			if depth == 0 {
+				return pos
+			}
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// operand возвращает имя колонки из левой части сравнения, которая заканчивается лексемой pos:
+// col, t.col, "col" или функция от одной колонки, например lower(col)
+func operand(tokens []token, pos int) string {
+	if pos >= 0 && tokens[pos].text == ")" {
+		pos = prevSignificant(tokens, pos)
+		if j := prevSignificant(tokens, pos); j < 0 || tokens[j].text != "(" && tokens[j].text != "." {
+			return ""
+		}
+	}
+
+	if pos >= 0 && (tokens[pos].kind == tokenWord || tokens[pos].kind == tokenIdent) {
+		return unquoteIdent(tokens[pos].text)
+	}
+
+	return ""
+}
+
+// insertColumns сопоставляет значения VALUES (...) списку колонок INSERT INTO t (...).
+// Элемент VALUES относится к колонке, если содержит ровно один плейсхолдер, например $1 или lower($1).
+func insertColumns(tokens []token, columns map[int]string) {
+	var (
+		names        []string
+		inNames      bool
+		inValues     bool
+		depth        int
+		item         int
+		placeholders int
+		single       int
+	)
+
+	for pos, t := range tokens {
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			continue
+		}
+
+		switch {
+		case t.kind == tokenWord && strings.EqualFold(t.text, "INSERT"):
+			names, inNames, inValues = nil, false, false
+		case t.kind == tokenWord && strings.EqualFold(t.text, "VALUES"):
+			inValues = true
+		case !inValues && names == nil && t.text == "(":
+			inNames = true
+			names = []string{}
+		case inNames && t.text == ")":
+			inNames = false
+		case inNames && (t.kind == tokenWord || t.kind == tokenIdent):
+			names = append(names, unquoteIdent(t.text))
+		case inValues && t.text == "(":
+			depth++
+			if depth == 1 {
+				item, placeholders = 0, 0
+			}
+		case inValues && depth > 0 && t.text == ")":
+			if depth == 1 {
+				mapInsertItem(names, item, placeholders, single, columns)
+			}
+			depth--
+		case inValues && depth == 1 && t.text == ",":
+			mapInsertItem(names, item, placeholders, single, columns)
+			item, placeholders = item+1, 0
+		case inValues && depth > 0 && t.kind == tokenPlaceholder:
+			placeholders++
+			single = pos
+		case inValues && depth == 0 && t.text != ",":
+			inValues = false
+		}
+	}
+}
+
+// mapInsertItem запоминает колонку для элемента VALUES, если в нём ровно один плейсхолдер
+func mapInsertItem(names []string, item, placeholders, single int, columns map[int]string) {
+	if placeholders == 1 && item < len(names) {
+		columns[single] = names[item]
+	}
+}
+
+// prevSignificant возвращает позицию предыдущей лексемы, не являющейся пробелом или комментарием
+func prevSignificant(tokens []token, pos int) int {
+	for pos--; pos >= 0; pos-- {
+		if tokens[pos].kind != tokenSpace && tokens[pos].kind != tokenComment {
+			return pos
+		}
+	}
+
+	return -1
+}
+
+func isComparison(s string) bool {
+	return strings.ContainsAny(s, "=<>!")
+}
+
+func unquoteIdent(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return strings.ReplaceAll(strings.Trim(s, `"`), `""`, `"`)
+	}
+
+	return s
+}
@@ -0,0 +1,137 @@
+package prettier
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPrettyRedactedColumns(t *testing.T) {
+	r := Redaction{Columns: SensitiveColumns}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []any
+		want  string
+	}{
+		{
+			name:  "comparison",
+			query: "SELECT id FROM users WHERE login = $1 AND password_hash = $2",
+			args:  []any{"bob", "x"},
+			want:  "SELECT id FROM users WHERE login = 'bob' AND password_hash = '[REDACTED]'",
+		},
+		{
+			name:  "qualified and quoted column",
+			query: `SELECT 1 FROM users u WHERE u."api_key" <> $1`,
+			args:  []any{"x"},
+			want:  `SELECT 1 FROM users u WHERE u."api_key" <> '[REDACTED]'`,
+		},
+		{
+			name:  "update",
+			query: "UPDATE users SET name = $1, secret = $2 WHERE id = $3",
+			args:  []any{"bob", "x", 1},
+			want:  "UPDATE users SET name = 'bob', secret = '[REDACTED]' WHERE id = 1",
+		},
+		{
+			name:  "insert",
+			query: "INSERT INTO users (name, token) VALUES ($1, lower($2))",
+			args:  []any{"bob", "x"},
+			want:  "INSERT INTO users (name, token) VALUES ('bob', lower('[REDACTED]'))",
+		},
+		{
+			name:  "in list",
+			query: "DELETE FROM sessions WHERE token IN ($1, $2) AND user_id IN ($3)",
+			args:  []any{"a", "b", 7},
+			want:  "DELETE FROM sessions WHERE token IN ('[REDACTED]', '[REDACTED]') AND user_id IN (7)",
+		},
+		{
+			name:  "not in list",
+			query: "SELECT 1 FROM t WHERE token NOT IN ($1)",
+			args:  []any{"a"},
+			want:  "SELECT 1 FROM t WHERE token NOT IN ('[REDACTED]')",
+		},
+		{
+			name:  "function on both sides",
+			query: "SELECT 1 FROM t WHERE lower(token) = lower($1) AND lower(name) = lower($2)",
+			args:  []any{"a", "bob"},
+			want:  "SELECT 1 FROM t WHERE lower(token) = lower('[REDACTED]') AND lower(name) = lower('bob')",
+		},
+		{
+			name:  "any",
+			query: "SELECT 1 FROM t WHERE token = ANY($1)",
+			args:  []any{[]string{"a"}},
+			want:  "SELECT 1 FROM t WHERE token = ANY('[REDACTED]')",
+		},
+		{
+			name:  "unrelated function arguments",
+			query: "SELECT f($1, $2)",
+			args:  []any{"a", "b"},
+			want:  "SELECT f('a', 'b')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrettyRedacted(tt.query, PlaceholderDollar, r, tt.args...); got != tt.want {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyRedactedArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Redaction
+		args []any
+		want string
+	}{
+		{name: "all", r: Redaction{All: true}, args: []any{1, "a"}, want: "SELECT '[REDACTED]', '[REDACTED]'"},
+		{name: "by number", r: Redaction{Args: []int{2}}, args: []any{1, "a"}, want: "SELECT 1, '[REDACTED]'"},
+		{name: "sensitive", args: []any{1, Redact("a")}, want: "SELECT 1, '[REDACTED]'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrettyRedacted("SELECT $1, $2", PlaceholderDollar, tt.r, tt.args...); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactionTruncates(t *testing.T) {
+	long := strings.Repeat("a", 10)
+	r := Redaction{MaxLen: 4}
+
+	tests := []struct {
+		name string
+		arg  any
+		want string
+	}{
+		{name: "short string", arg: "abc", want: "'abc'"},
+		{name: "string", arg: long, want: "'aaaa' /* truncated, 10 bytes */"},
+		{name: "string pointer", arg: &long, want: "'aaaa' /* truncated, 10 bytes */"},
+		{name: "multibyte", arg: "ааа", want: "'аа' /* truncated, 6 bytes */"},
+		{name: "bytes", arg: []byte("abcdef"), want: `'\x61626364'::bytea /* truncated, 6 bytes */`},
+		{name: "json", arg: json.RawMessage(`{"a":1}`), want: `'{"a"' /* truncated, 7 bytes */`},
+		{
+			name: "slice",
+			arg:  []string{"ab", long},
+			want: "ARRAY['ab', 'aaaa' /* truncated, 10 bytes */]",
+		},
+		{name: "map", arg: map[string]string{"a": long}, want: `'{"a"' /* truncated, 18 bytes */`},
+		{name: "valuer", arg: sql.NullString{String: long, Valid: true}, want: "'aaaa' /* truncated, 10 bytes */"},
+		{name: "number", arg: 1234567, want: "1234567"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.literal(tt.arg); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
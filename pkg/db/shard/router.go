@@ -0,0 +1,207 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+// router реализует db.DB, выбирая шард для каждого запроса: фиксированный
+// или по ключу из контекста, если shard меньше нуля
+type router struct {
+	client  *Client
+	shard   int
+	replica bool
+}
+
+// index возвращает номер шарда для запроса. Если ключа в контексте нет,
+// запрос внутри транзакции шарда выполняется на шарде этой транзакции.
+func (r *router) index(ctx context.Context) (int, error) {
+	if r.shard >= 0 {
+		return r.shard, nil
+	}
+
+	key, ok := KeyFromContext(ctx)
+	if !ok {
+		if tx, ok := ctx.Value(pg.TxKey).(*shardTx); ok {
+			return tx.index, nil
+		}
+
+		return 0, ErrNoShardKey
+	}
+
+	return r.client.Index(key)
+}
+
+// checkTx проверяет, что транзакция принадлежит шарду i, иначе запрос ушёл бы в чужую транзакцию
+func checkTx(tx pgx.Tx, i int) error {
+	if tx, ok := tx.(*shardTx); ok && tx.index != i {
+		return fmt.Errorf("%w: transaction on shard %d, query for shard %d", ErrCrossShard, tx.index, i)
+	}
+
+	return nil
+}
+
+// resolve выбирает DB шарда для запроса. Внутри транзакции шард обязан совпадать
+// с шардом этой транзакции.
+func (r *router) resolve(ctx context.Context) (db.DB, error) {
+	i, err := r.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, ok := ctx.Value(pg.TxKey).(pgx.Tx); ok {
+		if err = checkTx(tx, i); err != nil {
+			return nil, err
+		}
+	}
+
+	d := r.client.shards[i].DB()
+	if r.replica {
+		d = r.client.shards[i].Replica()
+	}
+
+	return &shardDB{DB: d, index: i}, nil
+}
+
+func (r *router) ScanOneContext(ctx context.Context, dest interface{}, q db.Query, args ...interface{}) error {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.ScanOneContext(ctx, dest, q, args...)
+}
+
+func (r *router) ScanAllContext(ctx context.Context, dest interface{}, q db.Query, args ...interface{}) error {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.ScanAllContext(ctx, dest, q, args...)
+}
+
+func (r *router) StreamContext(ctx context.Context, dest interface{}, q db.Query, fn func() error, args ...interface{}) error {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.StreamContext(ctx, dest, q, fn, args...)
+}
+
+func (r *router) ExecContext(ctx context.Context, q db.Query, args ...interface{}) (pgconn.CommandTag, error) {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return d.ExecContext(ctx, q, args...)
+}
+
+func (r *router) QueryContext(ctx context.Context, q db.Query, args ...interface{}) (pgx.Rows, error) {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.QueryContext(ctx, q, args...)
+}
+
+func (r *router) QueryRowContext(ctx context.Context, q db.Query, args ...interface{}) pgx.Row {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	return d.QueryRowContext(ctx, q, args...)
+}
+
+// BeginTx начинает транзакцию на шарде из контекста. Транзакция запоминает свой шард,
+// поэтому запросы, адресованные другому шарду, завершаются ошибкой ErrCrossShard.
+func (r *router) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	d, err := r.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.BeginTx(ctx, txOptions)
+}
+
+// JoinTx отказывает вложенному вызову TxManager, ключ шарда которого указывает на другой шард
+func (r *router) JoinTx(ctx context.Context, tx pgx.Tx) error {
+	i, err := r.index(ctx)
+	if err != nil {
+		return err
+	}
+
+	return checkTx(tx, i)
+}
+
+// Ping проверяет соединение с шардом или со всеми шардами, если шард выбирается по контексту
+func (r *router) Ping(ctx context.Context) error {
+	for i, s := range r.client.shards {
+		if r.shard >= 0 && r.shard != i {
+			continue
+		}
+
+		d := s.DB()
+		if r.replica {
+			d = s.Replica()
+		}
+
+		if err := d.Ping(ctx); err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Close ничего не делает: соединениями владеет Client
+func (r *router) Close() {}
+
+// shardDB DB одного шарда, транзакции которой помечены номером шарда
+type shardDB struct {
+	db.DB
+	index int
+}
+
+func (s *shardDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := s.DB.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardTx{Tx: tx, index: s.index}, nil
+}
+
+// shardTx транзакция, знающая свой шард
+type shardTx struct {
+	pgx.Tx
+	index int
+}
+
+// Begin помечает шардом и точки сохранения, чтобы проверка работала во вложенных транзакциях
+func (t *shardTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardTx{Tx: tx, index: t.index}, nil
+}
+
+// errRow строка, чтение которой возвращает ошибку выбора шарда
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -0,0 +1,133 @@
+package shard_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/mocks"
+	"github.com/ne4chelovek/chat_common/pkg/db/shard"
+	"github.com/ne4chelovek/chat_common/pkg/db/transaction"
+)
+
+// fakeDB DB шарда, которая начинает транзакции из tx и считает выполненные команды
+type fakeDB struct {
+	db.DB
+	tx    pgx.Tx
+	execs int
+}
+
+func (f *fakeDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return f.tx, nil
+}
+
+func (f *fakeDB) ExecContext(context.Context, db.Query, ...interface{}) (pgconn.CommandTag, error) {
+	f.execs++
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (f *fakeDB) QueryRowContext(context.Context, db.Query, ...interface{}) pgx.Row {
+	f.execs++
+	return nil
+}
+
+// fakeClient клиент одного шарда
+type fakeClient struct {
+	db.Client
+	db *fakeDB
+}
+
+func (c fakeClient) DB() db.DB {
+	return c.db
+}
+
+// newTestClient создаёт клиент из двух шардов с диапазонами ключей [..., 100) и [100, ...)
+func newTestClient(t *testing.T) (*shard.Client, []*fakeDB) {
+	t.Helper()
+
+	mc := minimock.NewController(t)
+
+	dbs := []*fakeDB{
+		{tx: mocks.NewTxMock(mc).CommitMock.Optional().Return(nil).RollbackMock.Optional().Return(nil)},
+		{tx: mocks.NewTxMock(mc).CommitMock.Optional().Return(nil).RollbackMock.Optional().Return(nil)},
+	}
+
+	c, err := shard.NewFromClients(shard.Range{Bounds: []int64{100}}, fakeClient{db: dbs[0]}, fakeClient{db: dbs[1]})
+	if err != nil {
+		t.Fatalf("NewFromClients: %v", err)
+	}
+
+	return c, dbs
+}
+
+func TestRoutesByKey(t *testing.T) {
+	c, dbs := newTestClient(t)
+
+	if _, err := c.DB().ExecContext(shard.WithKey(context.Background(), "150"), db.Query{Name: "test"}); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if dbs[0].execs != 0 || dbs[1].execs != 1 {
+		t.Fatalf("execs = [%d %d], want [0 1]", dbs[0].execs, dbs[1].execs)
+	}
+
+	if _, err := c.DB().ExecContext(context.Background(), db.Query{Name: "test"}); !errors.Is(err, shard.ErrNoShardKey) {
+		t.Fatalf("err = %v, want ErrNoShardKey", err)
+	}
+}
+
+func TestQueryInTransactionOnOtherShard(t *testing.T) {
+	c, dbs := newTestClient(t)
+	m := transaction.NewTransactionManager(c.DB())
+
+	err := m.ReadCommitted(shard.WithKey(context.Background(), "1"), func(ctx context.Context) error {
+		// без ключа запрос выполняется на шарде транзакции
+		if _, err := c.DB().ExecContext(ctx, db.Query{Name: "same shard"}); err != nil {
+			return err
+		}
+
+		other := shard.WithKey(ctx, "150")
+		if _, err := c.DB().ExecContext(other, db.Query{Name: "exec"}); !errors.Is(err, shard.ErrCrossShard) {
+			t.Errorf("ExecContext err = %v, want ErrCrossShard", err)
+		}
+		if err := c.DB().QueryRowContext(other, db.Query{Name: "query row"}).Scan(); !errors.Is(err, shard.ErrCrossShard) {
+			t.Errorf("QueryRowContext err = %v, want ErrCrossShard", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadCommitted: %v", err)
+	}
+
+	if dbs[0].execs != 1 || dbs[1].execs != 0 {
+		t.Fatalf("execs = [%d %d], want [1 0]", dbs[0].execs, dbs[1].execs)
+	}
+}
+
+func TestNestedTxManagerOnOtherShard(t *testing.T) {
+	c, _ := newTestClient(t)
+	m := transaction.NewTransactionManager(c.DB())
+
+	err := m.ReadCommitted(shard.WithKey(context.Background(), "1"), func(ctx context.Context) error {
+		called := false
+		err := m.ReadCommitted(shard.WithKey(ctx, "150"), func(context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, shard.ErrCrossShard) {
+			t.Errorf("nested err = %v, want ErrCrossShard", err)
+		}
+		if called {
+			t.Error("nested handler ran inside a transaction on another shard")
+		}
+
+		return m.ReadCommitted(shard.WithKey(ctx, "50"), func(context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatalf("ReadCommitted: %v", err)
+	}
+}
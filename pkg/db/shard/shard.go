@@ -0,0 +1,133 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+var (
+	// ErrNoShardKey ключа шарда нет ни в контексте, ни в аргументах
+	ErrNoShardKey = errors.New("shard: shard key is required but not found in context")
+	// ErrCrossShard запрос внутри транзакции адресован другому шарду
+	ErrCrossShard = errors.New("shard: cross-shard transactions are not supported")
+)
+
+type keyCtx struct{}
+
+// WithKey кладёт ключ шарда в контекст
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyCtx{}, key)
+}
+
+// KeyFromContext возвращает ключ шарда из контекста
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyCtx{}).(string)
+	return key, ok
+}
+
+// Client клиент, который хранит по пулу на каждый шард и выбирает шард по ключу.
+// Удовлетворяет интерфейсу db.Client: DB и Replica маршрутизируют запросы по ключу из контекста.
+type Client struct {
+	shards   []db.Client
+	strategy Strategy
+}
+
+// New создаёт клиента с шардом на каждый DSN. Опции применяются к каждому шарду.
+func New(ctx context.Context, dsns []string, strategy Strategy, opts ...pg.Option) (*Client, error) {
+	if len(dsns) == 0 {
+		return nil, errors.New("shard: at least one shard is required")
+	}
+	if err := validateStrategy(strategy, len(dsns)); err != nil {
+		return nil, err
+	}
+
+	c := &Client{strategy: strategy}
+	for i, dsn := range dsns {
+		client, err := pg.New(ctx, dsn, opts...)
+		if err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to connect to shard %d: %w", i, err)
+		}
+
+		c.shards = append(c.shards, client)
+	}
+
+	return c, nil
+}
+
+// NewFromClients создаёт шардированный клиент из уже созданных клиентов
+func NewFromClients(strategy Strategy, shards ...db.Client) (*Client, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("shard: at least one shard is required")
+	}
+	if err := validateStrategy(strategy, len(shards)); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		shards:   shards,
+		strategy: strategy,
+	}, nil
+}
+
+// Len возвращает число шардов
+func (c *Client) Len() int {
+	return len(c.shards)
+}
+
+// Index возвращает номер шарда для ключа
+func (c *Client) Index(key string) (int, error) {
+	i, err := c.strategy.Shard(key, len(c.shards))
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= len(c.shards) {
+		return 0, fmt.Errorf("shard: strategy returned shard %d out of %d", i, len(c.shards))
+	}
+
+	return i, nil
+}
+
+// Shard возвращает DB шарда для явно переданного ключа
+func (c *Client) Shard(key string) (db.DB, error) {
+	i, err := c.Index(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &router{client: c, shard: i}, nil
+}
+
+// DB возвращает DB, выбирающую шард по ключу из контекста (см. WithKey)
+func (c *Client) DB() db.DB {
+	return &router{client: c, shard: -1}
+}
+
+// Replica возвращает DB для чтения с реплик, выбирающую шард по ключу из контекста
+func (c *Client) Replica() db.DB {
+	return &router{client: c, shard: -1, replica: true}
+}
+
+// ReplicaStatus возвращает состояние реплик всех шардов
+func (c *Client) ReplicaStatus() []db.ReplicaStatus {
+	var statuses []db.ReplicaStatus
+	for _, s := range c.shards {
+		statuses = append(statuses, s.ReplicaStatus()...)
+	}
+
+	return statuses
+}
+
+// Close закрывает соединения со всеми шардами
+func (c *Client) Close() error {
+	var errs []error
+	for _, s := range c.shards {
+		errs = append(errs, s.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Strategy выбирает номер шарда по ключу
+type Strategy interface {
+	Shard(key string, shards int) (int, error)
+}
+
+// validator реализуют стратегии, конфигурацию которых можно проверить при создании клиента
+type validator interface {
+	validate(shards int) error
+}
+
+// validateStrategy проверяет конфигурацию стратегии для заданного числа шардов
+func validateStrategy(strategy Strategy, shards int) error {
+	if v, ok := strategy.(validator); ok {
+		return v.validate(shards)
+	}
+
+	return nil
+}
+
+// Hash распределяет ключи по шардам по хешу FNV-1a
+type Hash struct{}
+
+func (Hash) Shard(key string, shards int) (int, error) {
+	if shards <= 0 {
+		return 0, fmt.Errorf("hash strategy requires at least one shard, got %d", shards)
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum64() % uint64(shards)), nil
+}
+
+// Range распределяет числовые ключи по диапазонам. Bounds отсортированные по возрастанию
+// верхние границы (не включительно) всех шардов, кроме последнего: ключ меньше Bounds[0]
+// попадает в шард 0, ключ из [Bounds[i-1], Bounds[i]) в шард i, остальные в последний шард.
+type Range struct {
+	Bounds []int64
+}
+
+func (r Range) Shard(key string, shards int) (int, error) {
+	if err := r.validate(shards); err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("range strategy requires numeric key: %w", err)
+	}
+
+	return sort.Search(len(r.Bounds), func(i int) bool { return v < r.Bounds[i] }), nil
+}
+
+// validate проверяет, что границ на одну меньше, чем шардов, и они строго возрастают:
+// иначе sort.Search молча отправит ключи не в тот шард
+func (r Range) validate(shards int) error {
+	if len(r.Bounds) != shards-1 {
+		return fmt.Errorf("range strategy has %d bounds for %d shards", len(r.Bounds), shards)
+	}
+
+	for i := 1; i < len(r.Bounds); i++ {
+		if r.Bounds[i] <= r.Bounds[i-1] {
+			return fmt.Errorf("range strategy bounds must be strictly ascending, got %d after %d", r.Bounds[i], r.Bounds[i-1])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package shard
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+func TestHash(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+
+		got, err := Hash{}.Shard(key, 4)
+		if err != nil {
+			t.Fatalf("Shard(%q): %v", key, err)
+		}
+		if got < 0 || got >= 4 {
+			t.Fatalf("Shard(%q) = %d, want [0, 4)", key, got)
+		}
+
+		// один и тот же ключ всегда попадает в один шард
+		if again, _ := (Hash{}).Shard(key, 4); again != got {
+			t.Fatalf("Shard(%q) = %d then %d", key, got, again)
+		}
+	}
+
+	if _, err := (Hash{}).Shard("1", 0); err == nil {
+		t.Fatal("Shard with no shards succeeded")
+	}
+}
+
+func TestRange(t *testing.T) {
+	r := Range{Bounds: []int64{100, 200}}
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "-5", want: 0},
+		{key: "99", want: 0},
+		{key: "100", want: 1},
+		{key: "199", want: 1},
+		{key: "200", want: 2},
+		{key: "100500", want: 2},
+	}
+
+	for _, tt := range tests {
+		got, err := r.Shard(tt.key, 3)
+		if err != nil {
+			t.Fatalf("Shard(%q): %v", tt.key, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Shard(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRangeRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds []int64
+		shards int
+		key    string
+	}{
+		{name: "unsorted bounds", bounds: []int64{200, 100}, shards: 3, key: "150"},
+		{name: "duplicate bounds", bounds: []int64{100, 100}, shards: 3, key: "100"},
+		{name: "bounds count", bounds: []int64{100}, shards: 3, key: "1"},
+		{name: "non-numeric key", bounds: []int64{100}, shards: 2, key: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if i, err := (Range{Bounds: tt.bounds}).Shard(tt.key, tt.shards); err == nil {
+				t.Fatalf("Shard(%q) = %d, want error", tt.key, i)
+			}
+		})
+	}
+}
+
+func TestNewFromClientsValidatesStrategy(t *testing.T) {
+	shards := []db.Client{nil, nil, nil}
+
+	if _, err := NewFromClients(Range{Bounds: []int64{200, 100}}, shards...); err == nil {
+		t.Fatal("NewFromClients accepted unsorted range bounds")
+	}
+	if _, err := NewFromClients(Range{Bounds: []int64{100, 200}}, shards...); err != nil {
+		t.Fatalf("NewFromClients: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package transaction
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Callback функция, вызываемая после завершения транзакции
+type Callback func(ctx context.Context)
+
+type hooksKey struct{}
+
+// hooks колбэки, зарегистрированные внутри одной транзакции или точки сохранения
+type hooks struct {
+	mu         sync.Mutex
+	onCommit   []Callback
+	onRollback []Callback
+}
+
+// AfterCommit регистрирует колбэк, который будет вызван после успешного коммита самой внешней транзакции.
+// Вызывается изнутри db.Handler; вне транзакции возвращает ErrTxRequired.
+func AfterCommit(ctx context.Context, fn Callback) error {
+	h, ok := ctx.Value(hooksKey{}).(*hooks)
+	if !ok {
+		return ErrTxRequired
+	}
+
+	h.mu.Lock()
+	h.onCommit = append(h.onCommit, fn)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// AfterRollback регистрирует колбэк, который будет вызван после отката транзакции.
+// Вызывается изнутри db.Handler; вне транзакции возвращает ErrTxRequired.
+func AfterRollback(ctx context.Context, fn Callback) error {
+	h, ok := ctx.Value(hooksKey{}).(*hooks)
+	if !ok {
+		return ErrTxRequired
+	}
+
+	h.mu.Lock()
+	h.onRollback = append(h.onRollback, fn)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// withHooks кладёт в контекст новый набор колбэков
+func withHooks(ctx context.Context) (context.Context, *hooks) {
+	h := &hooks{}
+	return context.WithValue(ctx, hooksKey{}, h), h
+}
+
+// parentHooks возвращает набор колбэков транзакции из контекста, если он есть
+func parentHooks(ctx context.Context) *hooks {
+	h, _ := ctx.Value(hooksKey{}).(*hooks)
+	return h
+}
+
+// merge переносит колбэки освобождённой точки сохранения во внешнюю транзакцию
+func (h *hooks) merge(child *hooks) {
+	child.mu.Lock()
+	onCommit, onRollback := child.onCommit, child.onRollback
+	child.mu.Unlock()
+
+	h.mu.Lock()
+	h.onCommit = append(h.onCommit, onCommit...)
+	h.onRollback = append(h.onRollback, onRollback...)
+	h.mu.Unlock()
+}
+
+func (h *hooks) committed(ctx context.Context) {
+	h.mu.Lock()
+	funcs := h.onCommit
+	h.mu.Unlock()
+
+	runCallbacks(ctx, funcs)
+}
+
+func (h *hooks) rolledBack(ctx context.Context) {
+	h.mu.Lock()
+	funcs := h.onRollback
+	h.mu.Unlock()
+
+	runCallbacks(ctx, funcs)
+}
+
+// runCallbacks последовательно выполняет колбэки в порядке регистрации.
+// Паника в колбэке логируется и не мешает выполнению остальных.
+func runCallbacks(ctx context.Context, funcs []Callback) {
+	for _, fn := range funcs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Println("panic in transaction callback:", r)
+				}
+			}()
+
+			fn(ctx)
+		}()
+	}
+}
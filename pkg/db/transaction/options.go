@@ -0,0 +1,65 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrIsolationEscalation вложенный вызов запросил более строгий уровень изоляции, чем у внешней транзакции
+	ErrIsolationEscalation = errors.New("nested transaction requests stricter isolation level than outer transaction")
+	// ErrReadOnlyViolation вложенный вызов запросил режим READ WRITE внутри транзакции READ ONLY
+	ErrReadOnlyViolation = errors.New("nested transaction requests read-write access inside read-only transaction")
+)
+
+type optsKey struct{}
+
+// withTxOptions сохраняет параметры внешней транзакции в контексте для проверки вложенных вызовов
+func withTxOptions(ctx context.Context, opts pgx.TxOptions) context.Context {
+	return context.WithValue(ctx, optsKey{}, opts)
+}
+
+// validateNested проверяет, что вложенный вызов совместим с параметрами внешней транзакции.
+// Если параметры внешней транзакции неизвестны (транзакция положена в контекст вручную), проверка пропускается.
+func validateNested(ctx context.Context, opts pgx.TxOptions) error {
+	outer, ok := ctx.Value(optsKey{}).(pgx.TxOptions)
+	if !ok {
+		return nil
+	}
+
+	if isoRank(opts.IsoLevel) > isoRank(outer.IsoLevel) {
+		return fmt.Errorf("%w: %s inside %s", ErrIsolationEscalation, isoName(opts.IsoLevel), isoName(outer.IsoLevel))
+	}
+
+	if outer.AccessMode == pgx.ReadOnly && opts.AccessMode != pgx.ReadOnly {
+		return ErrReadOnlyViolation
+	}
+
+	return nil
+}
+
+// isoRank упорядочивает уровни изоляции по строгости. Пустой уровень соответствует
+// уровню сервера по умолчанию, то есть READ COMMITTED.
+func isoRank(level pgx.TxIsoLevel) int {
+	switch level {
+	case pgx.ReadUncommitted:
+		return 0
+	case pgx.RepeatableRead:
+		return 2
+	case pgx.Serializable:
+		return 3
+	default:
+		return 1
+	}
+}
+
+func isoName(level pgx.TxIsoLevel) string {
+	if level == "" {
+		return string(pgx.ReadCommitted)
+	}
+
+	return string(level)
+}
@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+var (
+	// ErrTxRequired обработчик с PropagationMandatory вызван вне транзакции
+	ErrTxRequired = errors.New("transaction required but none in context")
+	// ErrTxNotAllowed обработчик с PropagationNever вызван внутри транзакции
+	ErrTxNotAllowed = errors.New("transaction not allowed but found in context")
+)
+
+// WithPropagation выполняет обработчик с указанным режимом распространения.
+// Наличие транзакции определяется так же, как в transaction: по pg.TxKey в контексте.
+//
+// PropagationRequiresNew занимает второе соединение из пула на время работы внешней транзакции,
+// поэтому при маленьком пуле и большом числе параллельных вызовов возможна взаимная блокировка.
+func (m *manager) WithPropagation(ctx context.Context, p db.Propagation, opts pgx.TxOptions, f db.Handler) error {
+	_, inTx := ctx.Value(pg.TxKey).(pgx.Tx)
+
+	switch p {
+	case db.PropagationRequired:
+		return m.transaction(ctx, opts, f)
+	case db.PropagationRequiresNew:
+		return m.transaction(detach(ctx), opts, f)
+	case db.PropagationMandatory:
+		if !inTx {
+			return ErrTxRequired
+		}
+
+		return m.transaction(ctx, opts, f)
+	case db.PropagationNever:
+		if inTx {
+			return ErrTxNotAllowed
+		}
+
+		return f(ctx)
+	default:
+		return fmt.Errorf("unknown propagation mode: %d", p)
+	}
+}
+
+// detach убирает из контекста текущую транзакцию и её параметры,
+// чтобы следующий вызов начал независимую транзакцию
+func detach(ctx context.Context) context.Context {
+	ctx = pg.MakeContextTx(ctx, nil)
+	return context.WithValue(ctx, optsKey{}, nil)
+}
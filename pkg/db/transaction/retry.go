@@ -0,0 +1,90 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ne4chelovek/chat_common/pkg/db"
+)
+
+// Option настраивает менеджер транзакций
+type Option func(m *manager)
+
+// RetryPolicy политика повторения транзакций, завершившихся конфликтом.
+// Транзакция повторяется целиком: обработчик выполняется заново в новой транзакции.
+type RetryPolicy struct {
+	// MaxAttempts максимальное число попыток, включая первую. Значения меньше 2 отключают повторы.
+	MaxAttempts int
+	// Backoff возвращает паузу перед попыткой с номером attempt+1. nil означает повтор без паузы.
+	Backoff func(attempt int) time.Duration
+	// Retryable решает, нужно ли повторять транзакцию после ошибки. nil означает IsRetryable.
+	Retryable func(err error) bool
+}
+
+// noRetry политика по умолчанию: транзакция выполняется один раз
+var noRetry = RetryPolicy{MaxAttempts: 1}
+
+// DefaultRetryPolicy политика с тремя попытками и экспоненциальной паузой от 10ms,
+// повторяющая ошибки сериализации и дедлоки
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     ExponentialBackoff(10*time.Millisecond, time.Second),
+		Retryable:   IsRetryable,
+	}
+}
+
+// WithRetry включает повтор транзакций по указанной политике
+func WithRetry(p RetryPolicy) Option {
+	return func(m *manager) {
+		m.retry = p
+	}
+}
+
+// IsRetryable сообщает, что ошибка вызвана конфликтом сериализации (40001) или дедлоком (40P01).
+// Ошибки драйвера, ещё не обёрнутые в *db.Error (например, ошибка commit), классифицируются на месте.
+func IsRetryable(err error) bool {
+	err = db.WrapError("", err)
+	return errors.Is(err, db.ErrSerializationFailure) || errors.Is(err, db.ErrDeadlock)
+}
+
+// ExponentialBackoff возвращает паузу base*2^(attempt-1), ограниченную max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+
+		return d
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return IsRetryable(err)
+}
+
+// wait выдерживает паузу перед следующей попыткой, прерываясь при отмене контекста
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	if p.Backoff == nil {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
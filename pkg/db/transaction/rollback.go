@@ -0,0 +1,64 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultRollbackTimeout время, которое даётся откату транзакции по умолчанию
+const defaultRollbackTimeout = 5 * time.Second
+
+// PanicError паника, перехваченная при выполнении обработчика внутри транзакции.
+// Стек в текст ошибки не попадает, его можно получить из поля Stack.
+type PanicError struct {
+	Value any    // значение, переданное в panic
+	Stack []byte // стек вызовов в момент паники
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic recovered: %v", e.Value)
+}
+
+// Unwrap позволяет добраться до ошибки, если паника была вызвана со значением типа error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithRollbackTimeout задаёт таймаут отката транзакции. Откат выполняется на контексте,
+// не зависящем от отмены исходного, так как отмена контекста часто и есть причина ошибки.
+func WithRollbackTimeout(d time.Duration) Option {
+	return func(m *manager) {
+		m.rollbackTimeout = d
+	}
+}
+
+// WithRepanic включает повторный вызов panic после отката транзакции вместо возврата *PanicError
+func WithRepanic() Option {
+	return func(m *manager) {
+		m.repanic = true
+	}
+}
+
+// recovered превращает значение из recover в ошибку с сохранённым стеком
+func recovered(r any) error {
+	return &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// rollback откатывает транзакцию на неотменяемом контексте с собственным таймаутом.
+// Если откат не удался, возвращаемая ошибка содержит и исходную ошибку, и ошибку отката,
+// так что errors.Is срабатывает на обе.
+func (m *manager) rollback(ctx context.Context, tx pgx.Tx, err error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.rollbackTimeout)
+	defer cancel()
+
+	if errRollback := tx.Rollback(ctx); errRollback != nil {
+		return fmt.Errorf("%w (rollback failed: %w)", err, errRollback)
+	}
+
+	return err
+}
@@ -0,0 +1,77 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+)
+
+// WithSavepoints включает режим, в котором вложенные вызовы выполняются внутри SAVEPOINT.
+// При ошибке вложенного обработчика выполняется ROLLBACK TO SAVEPOINT, и внешняя транзакция
+// остаётся рабочей: вызывающий код может обработать ошибку и продолжить. При успехе точка
+// сохранения освобождается через RELEASE SAVEPOINT.
+func WithSavepoints() Option {
+	return func(m *manager) {
+		m.savepoints = true
+	}
+}
+
+// savepoint выполняет обработчик во вложенной транзакции на основе точки сохранения
+func (m *manager) savepoint(ctx context.Context, tx pgx.Tx, fn db.Handler) (err error) {
+	// pgx.Tx.Begin внутри транзакции создаёт SAVEPOINT
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("savepoint failed: %w", err)
+	}
+
+	// Колбэки отката получают контекст внешней транзакции: точка сохранения
+	// к моменту их вызова уже откатана и запросы через неё выполнить нельзя
+	callbackCtx := ctx
+
+	// Запросы вложенного обработчика выполняются через точку сохранения, а колбэки
+	// копятся отдельно, пока не станет ясно, переживёт ли точка сохранения откат
+	ctx = pg.MakeContextTx(ctx, sp)
+	parent := parentHooks(ctx)
+	ctx, child := withHooks(ctx)
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = recovered(r)
+		}
+
+		// откатываемся к точке сохранения, внешняя транзакция продолжает работу
+		if err != nil {
+			err = m.rollback(ctx, sp, err)
+
+			// работа внутри точки сохранения отменена: её колбэки отката срабатывают сразу,
+			// а колбэки коммита отбрасываются
+			child.rolledBack(callbackCtx)
+
+			if r != nil && m.repanic {
+				panic(r)
+			}
+
+			return
+		}
+
+		// освобождаем точку сохранения
+		if err = sp.Commit(ctx); err != nil {
+			err = fmt.Errorf("release savepoint failed: %w", err)
+		}
+
+		// колбэки переходят к внешней транзакции и сработают вместе с её завершением
+		if parent != nil {
+			parent.merge(child)
+		}
+	}()
+
+	if err = fn(ctx); err != nil {
+		err = fmt.Errorf("failed executing code inside savepoint: %w", err)
+	}
+
+	return err
+}
@@ -0,0 +1,89 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Settings ограничения времени выполнения транзакции. Нулевое значение поля означает,
+// что ограничение не задаётся и действует настройка сервера.
+type Settings struct {
+	// Timeout ограничивает время всей транзакции на стороне клиента через дедлайн контекста
+	Timeout time.Duration
+	// StatementTimeout применяется как SET LOCAL statement_timeout
+	StatementTimeout time.Duration
+	// LockTimeout применяется как SET LOCAL lock_timeout
+	LockTimeout time.Duration
+	// IdleInTransactionSessionTimeout применяется как SET LOCAL idle_in_transaction_session_timeout
+	IdleInTransactionSessionTimeout time.Duration
+}
+
+type settingsKey struct{}
+
+// WithSettings задаёт ограничения, применяемые по умолчанию ко всем транзакциям менеджера
+func WithSettings(s Settings) Option {
+	return func(m *manager) {
+		m.settings = s
+	}
+}
+
+// ContextWithSettings задаёт ограничения для транзакции, которая будет начата с этим контекстом.
+// Ненулевые поля перекрывают значения менеджера по умолчанию. На вложенные вызовы не влияет.
+func ContextWithSettings(ctx context.Context, s Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, s)
+}
+
+// settingsFor объединяет ограничения менеджера с ограничениями из контекста
+func (m *manager) settingsFor(ctx context.Context) Settings {
+	s := m.settings
+
+	override, ok := ctx.Value(settingsKey{}).(Settings)
+	if !ok {
+		return s
+	}
+
+	if override.Timeout > 0 {
+		s.Timeout = override.Timeout
+	}
+	if override.StatementTimeout > 0 {
+		s.StatementTimeout = override.StatementTimeout
+	}
+	if override.LockTimeout > 0 {
+		s.LockTimeout = override.LockTimeout
+	}
+	if override.IdleInTransactionSessionTimeout > 0 {
+		s.IdleInTransactionSessionTimeout = override.IdleInTransactionSessionTimeout
+	}
+
+	return s
+}
+
+// apply выполняет SET LOCAL для заданных ограничений сразу после начала транзакции
+func (s Settings) apply(ctx context.Context, tx pgx.Tx) error {
+	params := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", s.StatementTimeout},
+		{"lock_timeout", s.LockTimeout},
+		{"idle_in_transaction_session_timeout", s.IdleInTransactionSessionTimeout},
+	}
+
+	for _, p := range params {
+		if p.value <= 0 {
+			continue
+		}
+
+		// SET не принимает параметры запроса, поэтому значение подставляется числом миллисекунд.
+		// Округляем вверх: 0 означает отсутствие ограничения.
+		ms := (p.value + time.Millisecond - 1) / time.Millisecond
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL %s = %d", p.name, ms)); err != nil {
+			return fmt.Errorf("set local %s failed: %w", p.name, err)
+		}
+	}
+
+	return nil
+}
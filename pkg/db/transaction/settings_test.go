@@ -0,0 +1,62 @@
+package transaction_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ne4chelovek/chat_common/pkg/db/mocks"
+	"github.com/ne4chelovek/chat_common/pkg/db/transaction"
+)
+
+func TestSettingsApplySetLocal(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings transaction.Settings
+		want     []string
+	}{
+		{
+			name:     "milliseconds",
+			settings: transaction.Settings{StatementTimeout: 2 * time.Second, LockTimeout: 150 * time.Millisecond},
+			want:     []string{"SET LOCAL statement_timeout = 2000", "SET LOCAL lock_timeout = 150"},
+		},
+		{
+			name:     "sub-millisecond rounds up",
+			settings: transaction.Settings{StatementTimeout: 500 * time.Microsecond, LockTimeout: 1500 * time.Microsecond},
+			want:     []string{"SET LOCAL statement_timeout = 1", "SET LOCAL lock_timeout = 2"},
+		},
+		{
+			name:     "zero is not applied",
+			settings: transaction.Settings{IdleInTransactionSessionTimeout: time.Minute},
+			want:     []string{"SET LOCAL idle_in_transaction_session_timeout = 60000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := minimock.NewController(t)
+
+			var got []string
+			tx := mocks.NewTxMock(mc).
+				ExecMock.Set(func(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+					got = append(got, sql)
+					return pgconn.NewCommandTag("SET"), nil
+				}).
+				CommitMock.Return(nil)
+			transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+			m := transaction.NewTransactionManager(transactor)
+			ctx := transaction.ContextWithSettings(context.Background(), tt.settings)
+			if err := m.ReadCommitted(ctx, func(context.Context) error { return nil }); err != nil {
+				t.Fatalf("ReadCommitted: %v", err)
+			}
+
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Fatalf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
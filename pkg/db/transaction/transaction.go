@@ -5,49 +5,110 @@ import (
 	"github.com/ne4chelovek/chat_common/pkg/db/pg"
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
 type manager struct {
-	db db.Transactor
+	db              db.Transactor
+	retry           RetryPolicy
+	savepoints      bool
+	rollbackTimeout time.Duration
+	repanic         bool
+	settings        Settings
 }
 
 // NewTransactionManager Создаёт новый менеджер транзакций, который удовлетворяет интерфейсу db.TxManager
-func NewTransactionManager(db db.Transactor) db.TxManager {
-	return &manager{
-		db: db,
+func NewTransactionManager(db db.Transactor, opts ...Option) db.TxManager {
+	m := &manager{
+		db:              db,
+		retry:           noRetry,
+		rollbackTimeout: defaultRollbackTimeout,
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
 }
 
 // transaction основная функция, которая выполняет указанный пользователем обработчик в транзакции
-func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Handler) (err error) {
+func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Handler) error {
 	// Если это вложенная транзакция, пропускаем инициацию новой транзакции и выполняем обработчик.
-	tx, ok := ctx.Value(pg.TxKey).(pgx.Tx)
-	if ok {
+	// Вложенные вызовы никогда не повторяются: повторить можно только транзакцию целиком.
+	if tx, ok := ctx.Value(pg.TxKey).(pgx.Tx); ok {
+		if err := validateNested(ctx, opts); err != nil {
+			return err
+		}
+
+		if j, ok := m.db.(db.TxJoiner); ok {
+			if err := j.JoinTx(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		if m.savepoints {
+			return m.savepoint(ctx, tx, fn)
+		}
+
 		return fn(ctx)
 	}
 
+	// Повторяем транзакцию целиком в новой транзакции, пока это разрешает политика
+	for attempt := 1; ; attempt++ {
+		err := m.run(ctx, opts, fn)
+		if err == nil || attempt >= m.retry.MaxAttempts || !m.retry.retryable(err) {
+			return err
+		}
+
+		if errWait := m.retry.wait(ctx, attempt); errWait != nil {
+			return fmt.Errorf("%w (retry aborted: %v)", err, errWait)
+		}
+	}
+}
+
+// run выполняет обработчик в одной новой транзакции
+func (m *manager) run(ctx context.Context, opts pgx.TxOptions, fn db.Handler) (err error) {
+	// Колбэки после коммита и отката выполняются с исходным контекстом, без транзакции и её таймаута
+	callbackCtx := ctx
+	settings := m.settingsFor(ctx)
+
+	// Ограничиваем время всей транзакции, включая коммит
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+
 	// Стартуем новую транзакцию
-	tx, err = m.db.BeginTx(ctx, opts)
+	tx, err := m.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	// Кладём транзакцию в контекст
+	// Кладём транзакцию, её параметры и набор колбэков в контекст
 	ctx = pg.MakeContextTx(ctx, tx)
+	ctx = withTxOptions(ctx, opts)
+	ctx, h := withHooks(ctx)
 
 	// Настраиваем функцию отсрочки для отката или комита транзакции.
 	defer func() {
-		// восстанавливаемся после паники
-		if r := recover(); r != nil {
-			err = fmt.Errorf("panic recovered: %v", r)
+		// восстанавливаемся после паники, сохраняя стек
+		r := recover()
+		if r != nil {
+			err = recovered(r)
 		}
 
 		// откатываем транзакцию, если произошла ошибка
 		if err != nil {
-			if errRollback := tx.Rollback(ctx); errRollback != nil {
-				err = fmt.Errorf("errRollback: %w", err)
+			err = m.rollback(ctx, tx, err)
+			h.rolledBack(callbackCtx)
+
+			// при включённом режиме паника пробрасывается дальше уже после отката
+			if r != nil && m.repanic {
+				panic(r)
 			}
 
 			return
@@ -58,10 +119,20 @@ func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Han
 			err = tx.Commit(ctx)
 			if err != nil {
 				err = fmt.Errorf("tx commit failed: %w", err)
+				h.rolledBack(callbackCtx)
+
+				return
 			}
+
+			h.committed(callbackCtx)
 		}
 	}()
 
+	// Применяем серверные ограничения до выполнения обработчика; при ошибке транзакция откатывается
+	if err = settings.apply(ctx, tx); err != nil {
+		return err
+	}
+
 	// Выполните код внутри транзакции.
 	// Если функция терпит неудачу, возвращаем ошибку, и функция отсрочки выполняет откат
 	// или в противном случае транзакция коммитится.
@@ -75,4 +146,28 @@ func (m *manager) transaction(ctx context.Context, opts pgx.TxOptions, fn db.Han
 func (m *manager) ReadCommitted(ctx context.Context, f db.Handler) error {
 	txOpts := pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
 	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) RepeatableRead(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) Serializable(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.Serializable}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) ReadOnly(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) Deferrable(ctx context.Context, f db.Handler) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable}
+	return m.transaction(ctx, txOpts, f)
+}
+
+func (m *manager) WithOptions(ctx context.Context, opts pgx.TxOptions, f db.Handler) error {
+	return m.transaction(ctx, opts, f)
 }
\ No newline at end of file
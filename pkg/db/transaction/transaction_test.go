@@ -0,0 +1,262 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ne4chelovek/chat_common/pkg/db"
+	"github.com/ne4chelovek/chat_common/pkg/db/mocks"
+	"github.com/ne4chelovek/chat_common/pkg/db/pg"
+	"github.com/ne4chelovek/chat_common/pkg/db/transaction"
+)
+
+var errSerialization = &pgconn.PgError{Code: db.CodeSerializationFailure, Message: "could not serialize access"}
+
+func TestRetryRerunsHandlerInNewTransaction(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	first := mocks.NewTxMock(mc).RollbackMock.Return(nil)
+	second := mocks.NewTxMock(mc).CommitMock.Return(nil)
+	txs := []pgx.Tx{first, second}
+
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Times(2).Set(func(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+		tx := txs[0]
+		txs = txs[1:]
+		return tx, nil
+	})
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithRetry(transaction.RetryPolicy{MaxAttempts: 3}))
+
+	var seen []pgx.Tx
+	err := m.Serializable(context.Background(), func(ctx context.Context) error {
+		tx, _ := ctx.Value(pg.TxKey).(pgx.Tx)
+		seen = append(seen, tx)
+		if len(seen) == 1 {
+			return errSerialization
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Serializable: %v", err)
+	}
+
+	if len(seen) != 2 || seen[0] != first || seen[1] != second {
+		t.Fatalf("handler ran in %v, want first then second transaction", seen)
+	}
+}
+
+func TestRetryGivesUpOnOtherErrors(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	tx := mocks.NewTxMock(mc).RollbackMock.Return(nil)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Times(1).Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithRetry(transaction.DefaultRetryPolicy()))
+
+	errHandler := errors.New("validation failed")
+	calls := 0
+	err := m.ReadCommitted(context.Background(), func(context.Context) error {
+		calls++
+		return errHandler
+	})
+	if !errors.Is(err, errHandler) || calls != 1 {
+		t.Fatalf("err = %v, calls = %d; want handler error after one call", err, calls)
+	}
+}
+
+func TestNestedCallNeverRetries(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	// внешняя транзакция уже в контексте, поэтому новая не начинается и не завершается
+	transactor := mocks.NewTransactorMock(mc)
+	ctx := pg.MakeContextTx(context.Background(), mocks.NewTxMock(mc))
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithRetry(transaction.RetryPolicy{MaxAttempts: 3}))
+
+	calls := 0
+	err := m.ReadCommitted(ctx, func(context.Context) error {
+		calls++
+		return errSerialization
+	})
+	if !transaction.IsRetryable(err) {
+		t.Fatalf("err = %v, want serialization failure", err)
+	}
+	if calls != 1 {
+		t.Fatalf("nested handler ran %d times, want 1", calls)
+	}
+}
+
+func TestSavepointRollbackFiresOnlyInnerCallbacks(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	savepoint := mocks.NewTxMock(mc).RollbackMock.Return(nil)
+	tx := mocks.NewTxMock(mc).BeginMock.Return(savepoint, nil).CommitMock.Return(nil)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithSavepoints())
+
+	var fired []string
+	record := func(name string) transaction.Callback {
+		return func(context.Context) { fired = append(fired, name) }
+	}
+
+	errInner := errors.New("inner failed")
+	err := m.ReadCommitted(context.Background(), func(ctx context.Context) error {
+		_ = transaction.AfterCommit(ctx, record("outer commit"))
+		_ = transaction.AfterRollback(ctx, record("outer rollback"))
+
+		err := m.ReadCommitted(ctx, func(ctx context.Context) error {
+			_ = transaction.AfterCommit(ctx, record("inner commit"))
+			_ = transaction.AfterRollback(ctx, func(ctx context.Context) {
+				// колбэк отката получает контекст внешней транзакции, а не откатанной точки сохранения
+				if got, _ := ctx.Value(pg.TxKey).(pgx.Tx); got != tx {
+					t.Errorf("rollback callback tx = %v, want outer transaction", got)
+				}
+				fired = append(fired, "inner rollback")
+			})
+
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Errorf("nested err = %v, want %v", err, errInner)
+		}
+
+		// внешняя транзакция продолжает работу после отката точки сохранения
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadCommitted: %v", err)
+	}
+
+	want := []string{"inner rollback", "outer commit"}
+	if strings.Join(fired, ",") != strings.Join(want, ",") {
+		t.Fatalf("fired = %v, want %v", fired, want)
+	}
+}
+
+func TestSavepointReleaseMergesCallbacks(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	savepoint := mocks.NewTxMock(mc).CommitMock.Return(nil)
+	tx := mocks.NewTxMock(mc).BeginMock.Return(savepoint, nil).CommitMock.Return(nil)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithSavepoints())
+
+	var fired []string
+	err := m.ReadCommitted(context.Background(), func(ctx context.Context) error {
+		return m.ReadCommitted(ctx, func(ctx context.Context) error {
+			return transaction.AfterCommit(ctx, func(context.Context) {
+				if savepoint.CommitAfterCounter() != 1 || tx.CommitAfterCounter() != 1 {
+					t.Error("commit callback ran before the outer transaction committed")
+				}
+				fired = append(fired, "inner commit")
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("ReadCommitted: %v", err)
+	}
+
+	if len(fired) != 1 {
+		t.Fatalf("fired = %v, want inner commit once", fired)
+	}
+}
+
+func TestRollbackUsesDetachedContext(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	tx := mocks.NewTxMock(mc).RollbackMock.Set(func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			t.Errorf("rollback context is done: %v", ctx.Err())
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("rollback context has no timeout")
+		}
+
+		return nil
+	})
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithRollbackTimeout(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := m.ReadCommitted(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRollbackErrorKeepsOriginal(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	errRollback := errors.New("connection reset")
+	tx := mocks.NewTxMock(mc).RollbackMock.Return(errRollback)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor)
+
+	errHandler := errors.New("handler failed")
+	err := m.ReadCommitted(context.Background(), func(context.Context) error {
+		return errHandler
+	})
+	if !errors.Is(err, errHandler) || !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want both handler and rollback errors", err)
+	}
+}
+
+func TestPanicIsRecovered(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	tx := mocks.NewTxMock(mc).RollbackMock.Return(nil)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor)
+
+	err := m.ReadCommitted(context.Background(), func(context.Context) error {
+		panic("boom")
+	})
+
+	var panicErr *transaction.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("err = %v, want *PanicError", err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("panic error = %+v, want value and stack", panicErr)
+	}
+	if msg := err.Error(); msg != "panic recovered: boom" {
+		t.Fatalf("Error() = %q, want single line without stack", msg)
+	}
+}
+
+func TestRepanicAfterRollback(t *testing.T) {
+	mc := minimock.NewController(t)
+
+	tx := mocks.NewTxMock(mc).RollbackMock.Return(nil)
+	transactor := mocks.NewTransactorMock(mc).BeginTxMock.Return(tx, nil)
+
+	m := transaction.NewTransactionManager(transactor, transaction.WithRepanic())
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want boom", r)
+		}
+		if tx.RollbackAfterCounter() != 1 {
+			t.Fatal("transaction was not rolled back before repanic")
+		}
+	}()
+
+	_ = m.ReadCommitted(context.Background(), func(context.Context) error {
+		panic("boom")
+	})
+}